@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// shortCacheKeyLength is the number of characters of a cache key shown in listings.
+const shortCacheKeyLength = 12
+
+var pruneOlderThanFlag time.Duration
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of fetched sources",
+	Long: `Fetched sources are cached by URL and revision under $XDG_CACHE_HOME/pim
+and reused across installations and projects.`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached sources",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		entries, err := c.List()
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			fmt.Printf("The cache at %s is empty.\n", c.Dir())
+			return nil
+		}
+
+		fmt.Printf("Cached sources in %s:\n", c.Dir())
+		for _, entry := range entries {
+			fmt.Printf("  %s  %s@%s  (last used %s)\n",
+				entry.Key[:shortCacheKeyLength], entry.URL, lockfile.ShortRevision(entry.Revision), entry.LastUsed.Format(time.DateOnly))
+		}
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cached sources that were not used recently",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		pruned, err := c.Prune(pruneOlderThanFlag)
+		for _, entry := range pruned {
+			fmt.Printf("  removed %s@%s\n", entry.URL, lockfile.ShortRevision(entry.Revision))
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed %d cached source(s).\n", len(pruned))
+		return nil
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove all cached sources",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		if err := c.Clean(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed cache directory %s.\n", c.Dir())
+		return nil
+	},
+}
+
+func openCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(afero.NewOsFs(), dir), nil
+}
+
+func init() {
+	cachePruneCmd.Flags().DurationVar(
+		&pruneOlderThanFlag,
+		"older-than",
+		30*24*time.Hour,
+		"Remove sources that were not used for longer than this duration",
+	)
+
+	cacheCmd.AddCommand(cacheListCmd, cachePruneCmd, cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
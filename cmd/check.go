@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/installer"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [directory]",
+	Short: "Check that installed files are up to date",
+	Long: `Run the installation in memory and compare the result with the files on disk, without writing anything.
+
+Reports files that are missing, stale (out of date with the sources) or modified (edited manually
+since the last installation), and exits with a non-zero status when any file is out of date.`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := changeToDirectoryArg(args); err != nil {
+			return err
+		}
+
+		fs := afero.NewOsFs()
+		cfg, err := loadConfig(fs)
+		if err != nil {
+			return err
+		}
+
+		cacheDir, err := cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+
+		opts := installer.Options{
+			Config:       cfg,
+			UserPrompter: installer.NewAcceptAllPrompter(),
+			LockPath:     lockfile.PathForConfig(configPathFlag),
+			CacheDir:     cacheDir,
+			Jobs:         jobsFlag,
+			Quiet:        true,
+		}
+
+		drifts, err := installer.Check(fs, &opts)
+		if err != nil {
+			return fmt.Errorf("check failed: %w", err)
+		}
+
+		if len(drifts) == 0 {
+			fmt.Println("All installed files are up to date.")
+			return nil
+		}
+
+		for _, drift := range drifts {
+			fmt.Printf("  %-8s %s\n", drift.Kind, drift.Path)
+		}
+		return fmt.Errorf("%d file(s) are out of date, run 'pim install' to update them", len(drifts))
+	},
+}
+
+func init() {
+	checkCmd.Flags().StringVarP(
+		&configPathFlag,
+		"config",
+		"c",
+		DefaultConfigFileName,
+		"Path to configuration file",
+	)
+
+	rootCmd.AddCommand(checkCmd)
+}
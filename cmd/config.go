@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var resolvedFlag bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the configuration",
+	Long: `Print the configuration file.
+
+With --resolved, the effective configuration is printed instead: base configurations referenced by
+extends are merged, environment variables are interpolated and the implicit working_dir source is added.
+Source URLs are printed as written, so that tokens read from the environment are not shown.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := afero.NewOsFs()
+
+		if !resolvedFlag {
+			data, err := afero.ReadFile(fs, configPathFlag)
+			if err != nil {
+				return fmt.Errorf("failed to read config file: %w", err)
+			}
+			fmt.Print(string(data))
+			return nil
+		}
+
+		cfg, err := loadConfig(fs)
+		if err != nil {
+			return err
+		}
+
+		data, err := cfg.MarshalResolved()
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().StringVarP(
+		&configPathFlag,
+		"config",
+		"c",
+		DefaultConfigFileName,
+		"Path to configuration file",
+	)
+	configShowCmd.Flags().BoolVar(
+		&resolvedFlag,
+		"resolved",
+		false,
+		"Print the configuration with extends, variables and defaults resolved",
+	)
+
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/installer"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [directory]",
+	Short: "Show what install would change as a unified diff",
+	Long: `Run the installation in memory and print a unified diff between the current outputs
+and the files that would be generated, without writing anything.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := changeToDirectoryArg(args); err != nil {
+			return err
+		}
+
+		fs := afero.NewOsFs()
+		cfg, err := loadConfig(fs)
+		if err != nil {
+			return err
+		}
+
+		cacheDir, err := cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+
+		opts := installer.Options{
+			Config:       cfg,
+			UserPrompter: installer.NewAcceptAllPrompter(),
+			LockPath:     lockfile.PathForConfig(configPathFlag),
+			CacheDir:     cacheDir,
+			Jobs:         jobsFlag,
+			Quiet:        true,
+		}
+
+		plan, err := installer.PlanInstall(fs, &opts)
+		if err != nil {
+			return fmt.Errorf("diff failed: %w", err)
+		}
+
+		for _, target := range plan.Targets {
+			for _, change := range target.Changes {
+				if err := plan.WriteDiff(os.Stdout, change); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(
+		&configPathFlag,
+		"config",
+		"c",
+		DefaultConfigFileName,
+		"Path to configuration file",
+	)
+
+	rootCmd.AddCommand(diffCmd)
+}
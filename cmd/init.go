@@ -187,69 +187,49 @@ func discoverInstructionFiles(instructionsDir string) []string {
 	return files
 }
 
+// toolTarget is the name and the format of the target generated for a detected tool.
+type toolTarget struct {
+	name   string
+	format config.Format
+}
+
+// toolTargets maps the detected tools to the target generated for them.
+var toolTargets = map[reflect.Type]toolTarget{
+	tpagents.GhCopilotAgentType: {name: "copilot-instructions", format: config.FormatCopilot},
+	tpagents.GeminiCLIAgentType: {name: "gemini-instructions", format: config.FormatGemini},
+	tpagents.ManualAgentType:    {name: "manual-instructions", format: config.FormatCodex},
+}
+
 // generateConfig creates a config based on the selected tool and discovered files
 func generateConfig(tool tpagents.TPAgentTool, instructionsDir string, existingFiles []string) (*config.Config, error) {
 	cfg := config.NewConfig()
 
-	switch reflect.TypeOf(tool) {
-	case tpagents.GhCopilotAgentType:
-		target := config.Target{
-			Name:    "copilot-instructions",
-			Output:  ".github/copilot-instructions.md",
-			Include: []string{},
-		}
-
-		// Add existing files to include list
-		for _, file := range existingFiles {
-			// Skip the output file itself
-			if file == ".github/copilot-instructions.md" {
-				continue
-			}
-			target.Include = append(target.Include, file)
-		}
-
-		cfg.Targets = []config.Target{target}
-
-	case tpagents.GeminiCLIAgentType:
-		target := config.Target{
-			Name:    "gemini-instructions",
-			Output:  "GEMINI.md",
-			Include: []string{},
-		}
-
-		// Add existing files to include list
-		for _, file := range existingFiles {
-			// Skip the output file itself
-			if file == "GEMINI.md" {
-				continue
-			}
-			target.Include = append(target.Include, file)
-		}
-
-		cfg.Targets = []config.Target{target}
+	toolTarget, ok := toolTargets[reflect.TypeOf(tool)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tool: %s", tool.Descriptor())
+	}
+	profile, ok := config.LookupAgentProfile(toolTarget.format)
+	if !ok {
+		return nil, fmt.Errorf("unsupported tool: %s", tool.Descriptor())
+	}
 
-	case tpagents.ManualAgentType:
-		target := config.Target{
-			Name:    "manual-instructions",
-			Output:  "AGENTS.md",
-			Include: []string{},
-		}
+	target := config.Target{
+		Name:    toolTarget.name,
+		Format:  profile.Format,
+		Include: []string{},
+	}
 
-		// Add existing files to include list
-		for _, file := range existingFiles {
-			// Skip the output file itself
-			if file == "AGENTS.md" {
-				continue
-			}
-			target.Include = append(target.Include, file)
+	// Add existing files to include list
+	for _, file := range existingFiles {
+		// Skip the output file itself
+		if file == profile.Output {
+			continue
 		}
-
-		cfg.Targets = []config.Target{target}
-
-	default:
-		return nil, fmt.Errorf("unsupported tool: %s", tool.Descriptor())
+		target.Include = append(target.Include, file)
 	}
 
+	cfg.Targets = []config.Target{target}
+
 	return cfg, nil
 }
 
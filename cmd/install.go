@@ -3,57 +3,75 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/hubblew/pim/internal/cache"
 	"github.com/hubblew/pim/internal/config"
 	"github.com/hubblew/pim/internal/installer"
+	"github.com/hubblew/pim/internal/lockfile"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
 var configPathFlag string
 var forceFlag bool
+var dryRunFlag bool
+var offlineFlag bool
+
+// jobsFlag is also used by commands that do not expose the --jobs flag.
+var jobsFlag = defaultJobs
+
+const defaultJobs = 4
 
 const DefaultConfigFileName = "pim.yaml"
 
 var installCmd = &cobra.Command{
 	Use:   "install [directory]",
 	Short: "Install packages from sources to targets",
-	Long:  `Fetch sources and copy specified files to target directories.`,
-	Args:  cobra.MaximumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		dir := "."
-		if len(args) > 0 {
-			dir = args[0]
-		}
+	Long: `Fetch sources and copy specified files to target directories.
 
-		if err := os.Chdir(dir); err != nil {
-			return fmt.Errorf("failed to change to directory %s: %w", dir, err)
-		}
-		workingDir, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get working directory: %w", err)
-		}
+Fetched sources are pinned in a lockfile next to the configuration file (pim.lock),
+so subsequent installs reproduce exactly the same state.
 
-		fs := afero.NewOsFs()
-		if _, err := fs.Stat(configPathFlag); os.IsNotExist(err) {
-			return fmt.Errorf("configuration file not found: %s", configPathFlag)
+With --offline, remote sources are never fetched: they are installed from the local cache at the
+revision locked in pim.lock, and the installation fails if a source is not locked or not cached.
+
+With --dry-run, nothing is written and the files that would be created, updated or deleted are listed per target.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := changeToDirectoryArg(args); err != nil {
+			return err
 		}
 
-		cfg, err := config.LoadConfig(fs, configPathFlag, workingDir)
+		fs := afero.NewOsFs()
+		cfg, err := loadConfig(fs)
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return err
 		}
 
-		var prompter installer.UserPrompter
-		if forceFlag {
-			prompter = installer.NewAcceptAllPrompter()
-		} else {
-			prompter = installer.NewInteractivePrompter()
+		cacheDir, err := cache.DefaultDir()
+		if err != nil {
+			return err
 		}
 
 		opts := installer.Options{
 			Config:       cfg,
-			UserPrompter: prompter,
+			UserPrompter: newUserPrompter(),
+			LockPath:     lockfile.PathForConfig(configPathFlag),
+			CacheDir:     cacheDir,
+			Offline:      offlineFlag,
+			Jobs:         jobsFlag,
+		}
+
+		if dryRunFlag {
+			opts.UserPrompter = installer.NewAcceptAllPrompter()
+			opts.Quiet = true
+			plan, err := installer.PlanInstall(fs, &opts)
+			if err != nil {
+				return fmt.Errorf("installation failed: %w", err)
+			}
+			printPlan(plan)
+			return nil
 		}
 
 		if err := installer.NewInstaller(fs).Install(&opts); err != nil {
@@ -64,21 +82,138 @@ var installCmd = &cobra.Command{
 	},
 }
 
-func init() {
-	installCmd.Flags().StringVarP(
+func printPlan(plan *installer.Plan) {
+	for _, target := range plan.Targets {
+		outputs := strings.Join(target.Outputs, ", ")
+		if len(target.Changes) == 0 {
+			fmt.Printf("Target '%s' (%s): no changes\n", target.Name, outputs)
+			continue
+		}
+
+		fmt.Printf("Target '%s' (%s):\n", target.Name, outputs)
+		for _, change := range target.Changes {
+			fmt.Printf("  %-8s %s\n", change.Kind, change.Path)
+		}
+	}
+}
+
+// changeToDirectoryArg changes to the directory given as the optional argument of a command, if any.
+func changeToDirectoryArg(args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to change to directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// loadConfig loads the configuration file selected with the --config flag
+// relative to the current working directory.
+func loadConfig(fs afero.Fs) (*config.Config, error) {
+	return loadUpdatedConfig(fs, nil)
+}
+
+// loadUpdatedConfig loads the configuration like loadConfig. Base configurations of the sources for which update
+// reports true are loaded at the latest revision of the source instead of the locked one, as pim update installs
+// them. update may be nil.
+func loadUpdatedConfig(fs afero.Fs, update func(sourceName string) bool) (*config.Config, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	if _, err := fs.Stat(configPathFlag); os.IsNotExist(err) {
+		return nil, fmt.Errorf("configuration file not found: %s", configPathFlag)
+	}
+
+	resolve, err := newSourceFileResolver(fs, configPathFlag, update)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadConfigWithResolver(fs, configPathFlag, workingDir, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// newSourceFileResolver returns a resolver that fetches the sources of base configurations like the sources
+// of an installation, pinned in the lockfile of the configuration at configPath. Sources for which update
+// reports true are re-resolved to their latest revision; update may be nil.
+func newSourceFileResolver(
+	fs afero.Fs,
+	configPath string,
+	update func(sourceName string) bool,
+) (config.SourceFileResolver, error) {
+	cacheDir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(source config.Source, file string) (string, error) {
+		options := &installer.Options{
+			LockPath: lockfile.PathForConfig(configPath),
+			CacheDir: cacheDir,
+			Offline:  offlineFlag,
+		}
+		if update != nil && update(source.Name) {
+			options.Update = []string{source.Name}
+		}
+		return installer.ResolveSourceFile(fs, options, source, file)
+	}, nil
+}
+
+func newUserPrompter() installer.UserPrompter {
+	if forceFlag {
+		return installer.NewAcceptAllPrompter()
+	}
+	return installer.NewInteractivePrompter()
+}
+
+// addInstallFlags registers the flags shared by commands that run an installation.
+func addInstallFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(
 		&configPathFlag,
 		"config",
 		"c",
 		DefaultConfigFileName,
 		"Path to configuration file",
 	)
-	installCmd.Flags().BoolVarP(
+	cmd.Flags().BoolVarP(
 		&forceFlag,
 		"force",
 		"f",
 		false,
 		"Force overwrite existing files without prompting",
 	)
+	cmd.Flags().IntVarP(
+		&jobsFlag,
+		"jobs",
+		"j",
+		defaultJobs,
+		"Maximum number of sources fetched concurrently",
+	)
+}
+
+func init() {
+	addInstallFlags(installCmd)
+	installCmd.Flags().BoolVar(
+		&dryRunFlag,
+		"dry-run",
+		false,
+		"Show the files that would be created, updated or deleted without writing anything",
+	)
+	installCmd.Flags().BoolVar(
+		&offlineFlag,
+		"offline",
+		false,
+		"Install sources from the cache at their locked revision without network access",
+	)
 
 	rootCmd.AddCommand(installCmd)
 }
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hubblew/pim/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of the configuration file",
+	Long: `Print the JSON schema of pim.yaml, derived from the configuration types of this version of PIM.
+
+Editors use the schema for completion and validation, e.g. with a comment at the top of pim.yaml:
+
+  # yaml-language-server: $schema=./schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := config.Schema()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(schema))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/installer"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [source...]",
+	Short: "Update locked sources to their latest revision",
+	Long: `Re-resolve the given sources (or all sources when none are given) to their latest revision,
+reinstall all targets and rewrite the lockfile.
+
+Prints the old and new revision of every updated source together with the included files that changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := afero.NewOsFs()
+		// Base configurations are loaded from the updated revision of their source, so that the installed
+		// targets match what a later install from the new lockfile produces.
+		cfg, err := loadUpdatedConfig(fs, func(sourceName string) bool {
+			return len(args) == 0 || slices.Contains(args, sourceName)
+		})
+		if err != nil {
+			return err
+		}
+
+		var sourceNames []string
+		for _, source := range cfg.Sources {
+			sourceNames = append(sourceNames, source.Name)
+		}
+		for _, name := range args {
+			if !slices.Contains(sourceNames, name) {
+				return fmt.Errorf("unknown source: %s", name)
+			}
+		}
+		if len(args) > 0 {
+			sourceNames = args
+		}
+
+		lockPath := lockfile.PathForConfig(configPathFlag)
+		before, err := lockfile.Load(fs, lockPath)
+		if err != nil {
+			return err
+		}
+
+		cacheDir, err := cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+
+		opts := installer.Options{
+			Config:       cfg,
+			UserPrompter: newUserPrompter(),
+			LockPath:     lockPath,
+			CacheDir:     cacheDir,
+			Jobs:         jobsFlag,
+			Update:       sourceNames,
+		}
+
+		if err := installer.NewInstaller(fs).Install(&opts); err != nil {
+			return fmt.Errorf("update failed: %w", err)
+		}
+
+		after, err := lockfile.Load(fs, lockPath)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			// Local sources are never locked, so only report the ones that ended up in the lockfile.
+			sourceNames = nil
+			for _, source := range after.Sources {
+				sourceNames = append(sourceNames, source.Name)
+			}
+		}
+
+		printUpdateSummary(lockfile.Diff(before, after, sourceNames))
+		return nil
+	},
+}
+
+func printUpdateSummary(changes []lockfile.SourceChange) {
+	fmt.Println("\nUpdate summary:")
+
+	for _, change := range changes {
+		switch {
+		case change.OldRevision == "" && change.NewRevision == "":
+			fmt.Printf("  %s: local source, not locked\n", change.Name)
+		case !change.Changed():
+			fmt.Printf("  %s: %s (up to date)\n", change.Name, lockfile.ShortRevision(change.NewRevision))
+		case change.OldRevision == "":
+			fmt.Printf("  %s: locked at %s\n", change.Name, lockfile.ShortRevision(change.NewRevision))
+		default:
+			fmt.Printf("  %s: %s -> %s\n", change.Name, lockfile.ShortRevision(change.OldRevision), lockfile.ShortRevision(change.NewRevision))
+		}
+
+		for _, file := range change.Files {
+			fmt.Printf("    %s %s\n", file.Status, file.Path)
+		}
+	}
+}
+
+func init() {
+	addInstallFlags(updateCmd)
+
+	rootCmd.AddCommand(updateCmd)
+}
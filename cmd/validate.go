@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/installer"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Validate configuration files",
+	Long: `Check configuration files against the schema and report all problems with their line and column.
+
+Unknown keys, values of the wrong type, invalid strategies and options, references to unknown sources and
+includes without wildcards that do not exist in local sources are reported. Remote sources are never fetched:
+base configurations that extends loads from a remote source are read from the cache at their locked revision,
+and a base configuration that is not cached yet is reported as a problem until 'pim install' caches it.
+
+Without arguments, the file selected with --config is validated. The command exits with a non-zero status
+when any problem is found, so it can be used as a pre-commit hook.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			paths = []string{configPathFlag}
+		}
+
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		fs := afero.NewOsFs()
+		count := 0
+		for _, path := range paths {
+			resolve, err := newCachedSourceFileResolver(fs, path)
+			if err != nil {
+				return err
+			}
+
+			problems, err := config.ValidateFile(fs, path, workingDir, resolve)
+			if err != nil {
+				return fmt.Errorf("failed to validate %s: %w", path, err)
+			}
+
+			for _, problem := range problems {
+				fmt.Printf("%s:%s\n", path, problem)
+			}
+			count += len(problems)
+		}
+
+		if count > 0 {
+			return fmt.Errorf("%d problem(s) found", count)
+		}
+		return nil
+	},
+}
+
+// newCachedSourceFileResolver returns a resolver that reads the files of remote sources from the cache at the
+// revision locked for the configuration at configPath, without fetching anything.
+func newCachedSourceFileResolver(fs afero.Fs, configPath string) (config.SourceFileResolver, error) {
+	cacheDir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(source config.Source, file string) (string, error) {
+		return installer.ResolveSourceFile(fs, &installer.Options{
+			LockPath: lockfile.PathForConfig(configPath),
+			CacheDir: cacheDir,
+			Offline:  true,
+		}, source, file)
+	}, nil
+}
+
+func init() {
+	validateCmd.Flags().StringVarP(
+		&configPathFlag,
+		"config",
+		"c",
+		DefaultConfigFileName,
+		"Path to configuration file",
+	)
+
+	rootCmd.AddCommand(validateCmd)
+}
@@ -3,14 +3,18 @@ module github.com/hubblew/pim
 go 1.25.4
 
 require (
+	github.com/Masterminds/semver/v3 v3.4.0
+	github.com/bmatcuk/doublestar/v4 v4.10.0
 	github.com/charmbracelet/bubbles v0.21.0
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/glamour v0.10.0
 	github.com/charmbracelet/lipgloss v1.1.1-0.20250404203927-76690c660834
 	github.com/goccy/go-yaml v1.18.0
 	github.com/hashicorp/go-getter v1.8.3
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/afero v1.15.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/term v0.34.0
 )
 
 require (
@@ -79,7 +83,6 @@ require (
 	golang.org/x/net v0.43.0 // indirect
 	golang.org/x/oauth2 v0.27.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
-	golang.org/x/term v0.34.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
 	google.golang.org/api v0.114.0 // indirect
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/afero"
+)
+
+const (
+	entryFileName  = "entry.yaml"
+	sourceDirName  = "source"
+	stagingPrefix  = "tmp-"
+	keyLength      = 32
+	dirPermissions = 0755
+)
+
+// Entry is a fetched source stored in the cache.
+type Entry struct {
+	Key      string    `yaml:"-"`
+	URL      string    `yaml:"url"`
+	Revision string    `yaml:"revision"`
+	Fetched  time.Time `yaml:"fetched"`
+	LastUsed time.Time `yaml:"lastUsed"`
+
+	dir string
+}
+
+// SourceDir returns the directory that contains the fetched source.
+func (e *Entry) SourceDir() string {
+	return filepath.Join(e.dir, sourceDirName)
+}
+
+// Cache stores fetched sources keyed by their URL and resolved revision,
+// so they can be reused across installations and projects.
+type Cache struct {
+	fs  afero.Fs
+	dir string
+	now func() time.Time
+}
+
+func New(fs afero.Fs, dir string) *Cache {
+	return &Cache{
+		fs:  fs,
+		dir: dir,
+		now: time.Now,
+	}
+}
+
+// DefaultDir returns the default cache directory, $XDG_CACHE_HOME/pim or the platform equivalent
+// when XDG_CACHE_HOME is not set.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "pim"), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return filepath.Join(dir, "pim"), nil
+}
+
+// Dir returns the root directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Fs returns the filesystem the cache is stored on.
+func (c *Cache) Fs() afero.Fs {
+	return c.fs
+}
+
+// Key returns the cache key of a source URL at the given revision.
+func Key(url, revision string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + revision))
+	return hex.EncodeToString(sum[:])[:keyLength]
+}
+
+// Lookup returns the cached entry of the source URL at the given revision and marks it as used.
+func (c *Cache) Lookup(url, revision string) (*Entry, bool) {
+	entry, err := c.readEntry(Key(url, revision))
+	if err != nil || entry.URL != url || entry.Revision != revision {
+		return nil, false
+	}
+
+	entry.LastUsed = c.now()
+	if err := c.writeEntry(entry.dir, entry); err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Store adds a source to the cache. fetch downloads the source into the given directory
+// and returns its revision. An existing entry for the same URL and revision is replaced.
+func (c *Cache) Store(url string, fetch func(dir string) (string, error)) (*Entry, error) {
+	if err := c.fs.MkdirAll(c.dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory '%s': %w", c.dir, err)
+	}
+
+	stagingDir, err := afero.TempDir(c.fs, c.dir, stagingPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache staging directory: %w", err)
+	}
+	defer func() {
+		_ = c.fs.RemoveAll(stagingDir)
+	}()
+
+	revision, err := fetch(filepath.Join(stagingDir, sourceDirName))
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.now()
+	entry := &Entry{
+		Key:      Key(url, revision),
+		URL:      url,
+		Revision: revision,
+		Fetched:  now,
+		LastUsed: now,
+		dir:      filepath.Join(c.dir, Key(url, revision)),
+	}
+	if err := c.writeEntry(stagingDir, entry); err != nil {
+		return nil, err
+	}
+
+	if err := c.fs.RemoveAll(entry.dir); err != nil {
+		return nil, fmt.Errorf("failed to replace cache entry '%s': %w", entry.Key, err)
+	}
+	if err := c.fs.Rename(stagingDir, entry.dir); err != nil {
+		return nil, fmt.Errorf("failed to store cache entry '%s': %w", entry.Key, err)
+	}
+
+	return entry, nil
+}
+
+// List returns all cache entries sorted by URL and revision.
+func (c *Cache) List() ([]Entry, error) {
+	infos, err := afero.ReadDir(c.fs, c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory '%s': %w", c.dir, err)
+	}
+
+	var entries []Entry
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), stagingPrefix) {
+			continue
+		}
+
+		entry, err := c.readEntry(info.Name())
+		if err != nil {
+			// Entries without metadata were not stored completely.
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Revision < entries[j].Revision
+	})
+
+	return entries, nil
+}
+
+// Prune removes the entries that were not used within the given duration and returns them.
+func (c *Cache) Prune(maxAge time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := c.now().Add(-maxAge)
+
+	var pruned []Entry
+	for _, entry := range entries {
+		if entry.LastUsed.After(cutoff) {
+			continue
+		}
+		if err := c.fs.RemoveAll(entry.dir); err != nil {
+			return pruned, fmt.Errorf("failed to remove cache entry '%s': %w", entry.Key, err)
+		}
+		pruned = append(pruned, entry)
+	}
+
+	return pruned, nil
+}
+
+// Clean removes the whole cache.
+func (c *Cache) Clean() error {
+	if err := c.fs.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to remove cache directory '%s': %w", c.dir, err)
+	}
+	return nil
+}
+
+func (c *Cache) readEntry(key string) (*Entry, error) {
+	dir := filepath.Join(c.dir, key)
+
+	data, err := afero.ReadFile(c.fs, filepath.Join(dir, entryFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := yaml.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry '%s': %w", key, err)
+	}
+	entry.Key = key
+	entry.dir = dir
+
+	return &entry, nil
+}
+
+func (c *Cache) writeEntry(dir string, entry *Entry) error {
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := afero.WriteFile(c.fs, filepath.Join(dir, entryFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry '%s': %w", entry.Key, err)
+	}
+	return nil
+}
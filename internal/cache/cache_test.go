@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func storeFile(t *testing.T, c *Cache, url, revision, content string) *Entry {
+	t.Helper()
+
+	entry, err := c.Store(url, func(dir string) (string, error) {
+		return revision, afero.WriteFile(c.fs, filepath.Join(dir, "file.md"), []byte(content), 0644)
+	})
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	return entry
+}
+
+func TestStoreAndLookup(t *testing.T) {
+	c := New(afero.NewMemMapFs(), "/cache")
+
+	if _, ok := c.Lookup("github.com/org/repo", "abc"); ok {
+		t.Fatal("expected lookup in empty cache to miss")
+	}
+
+	stored := storeFile(t, c, "github.com/org/repo", "abc", "content")
+	if stored.Key != Key("github.com/org/repo", "abc") {
+		t.Errorf("unexpected key %q", stored.Key)
+	}
+
+	entry, ok := c.Lookup("github.com/org/repo", "abc")
+	if !ok {
+		t.Fatal("expected lookup to hit")
+	}
+	content, err := afero.ReadFile(c.fs, filepath.Join(entry.SourceDir(), "file.md"))
+	if err != nil || string(content) != "content" {
+		t.Errorf("expected cached content, got %q, %v", content, err)
+	}
+
+	if _, ok := c.Lookup("github.com/org/repo", "def"); ok {
+		t.Error("expected lookup of another revision to miss")
+	}
+	if _, ok := c.Lookup("github.com/org/other", "abc"); ok {
+		t.Error("expected lookup of another url to miss")
+	}
+}
+
+func TestStoreFailureLeavesNoEntry(t *testing.T) {
+	c := New(afero.NewMemMapFs(), "/cache")
+
+	_, err := c.Store("github.com/org/repo", func(dir string) (string, error) {
+		return "", errors.New("network unreachable")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %v", entries)
+	}
+	infos, _ := afero.ReadDir(c.fs, "/cache")
+	if len(infos) != 0 {
+		t.Errorf("expected staging directory to be removed, got %d entries", len(infos))
+	}
+}
+
+func TestListPruneClean(t *testing.T) {
+	now := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	c := New(afero.NewMemMapFs(), "/cache")
+
+	c.now = func() time.Time { return now.AddDate(0, 0, -20) }
+	storeFile(t, c, "github.com/org/b", "1", "old")
+	c.now = func() time.Time { return now }
+	storeFile(t, c, "github.com/org/a", "2", "new")
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].URL != "github.com/org/a" || entries[1].URL != "github.com/org/b" {
+		t.Fatalf("expected entries sorted by url, got %v", entries)
+	}
+
+	pruned, err := c.Prune(10 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].URL != "github.com/org/b" {
+		t.Errorf("expected old entry to be pruned, got %v", pruned)
+	}
+	if _, ok := c.Lookup("github.com/org/a", "2"); !ok {
+		t.Error("expected recent entry to be kept")
+	}
+
+	if err := c.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if entries, _ := c.List(); len(entries) != 0 {
+		t.Errorf("expected empty cache after clean, got %v", entries)
+	}
+}
@@ -2,15 +2,37 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
-	"github.com/goccy/go-yaml"
+	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/afero"
 )
 
+// Fields of the configuration types are documented for the JSON schema with struct tags: description,
+// examples separated by '|', and jsonschema with comma-separated keywords (required, minLength=N, minimum=N,
+// maximum=N, const=V, default=V). See Schema.
 type Source struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Name string `yaml:"name" jsonschema:"required,minLength=1" description:"Unique identifier for the source" examples:"org-prompts|github-repo|local-dir"`
+	URL  string `yaml:"url" jsonschema:"required" description:"Local directory path or git repository URL" examples:"/path/to/directory|./relative/path|https://github.com/user/repo.git|git@github.com:user/repo.git"`
+	// Ref is a branch, tag or commit of a git source.
+	Ref string `yaml:"ref,omitempty" description:"Git branch, tag or commit to fetch (git sources only, cannot be combined with version)" examples:"main|v1.2.0|3f1c2e9d4b5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d"`
+	// Version is a semver constraint resolved against the tags of a git source.
+	Version string `yaml:"version,omitempty" description:"Semver constraint resolved against the repository tags (git sources only, cannot be combined with ref)" examples:"^1.2|~1.2.3|>= 1.0, < 2.0"`
+
+	// rawURL is the URL as written in the configuration file, when interpolation changed it.
+	rawURL string
+}
+
+// RawURL returns the URL as written in the configuration file, before environment variables were expanded.
+// It identifies the source in the lockfile and the cache and is the URL that is displayed, so that secrets
+// such as tokens read from the environment are neither persisted nor printed.
+func (s Source) RawURL() string {
+	if s.rawURL != "" {
+		return s.rawURL
+	}
+	return s.URL
 }
 
 const DefaultSourceName = "working_dir"
@@ -21,25 +43,159 @@ const (
 	StrategyFlatten  StrategyType = "flatten"
 	StrategyPreserve StrategyType = "preserve"
 	StrategyConcat   StrategyType = "concat"
+	// StrategyCursorRules converts each included file into a Cursor rule file (.mdc).
+	StrategyCursorRules StrategyType = "cursor-rules"
+	// StrategyCopilotInstructions writes each included file as a GitHub Copilot ".instructions.md" file.
+	StrategyCopilotInstructions StrategyType = "copilot-instructions"
+	// StrategyWindsurfRules converts each included file into a Windsurf rule file.
+	StrategyWindsurfRules StrategyType = "windsurf-rules"
+	// StrategyClineRules converts each included file into a Cline rule file.
+	StrategyClineRules StrategyType = "cline-rules"
+)
+
+// StrategyTypes lists the valid strategy types.
+var StrategyTypes = []StrategyType{
+	StrategyFlatten, StrategyPreserve, StrategyConcat, StrategyCursorRules, StrategyCopilotInstructions,
+	StrategyWindsurfRules, StrategyClineRules,
+}
+
+// FrontmatterPolicy defines how the concat strategy handles the frontmatter of included files.
+type FrontmatterPolicy string
+
+const (
+	// FrontmatterStrip removes the frontmatter of included files from the output.
+	FrontmatterStrip FrontmatterPolicy = "strip"
+	// FrontmatterMerge removes the frontmatter of included files and merges selected keys into the output header.
+	FrontmatterMerge FrontmatterPolicy = "merge"
 )
 
+// FrontmatterPolicies lists the valid frontmatter policies.
+var FrontmatterPolicies = []FrontmatterPolicy{FrontmatterStrip, FrontmatterMerge}
+
+// AttributionStyle defines how the concat strategy marks the origin of each included file.
+type AttributionStyle string
+
+const (
+	// AttributionNone does not mark the origin of included files.
+	AttributionNone AttributionStyle = "none"
+	// AttributionComment precedes each included file with an HTML comment naming its origin.
+	AttributionComment AttributionStyle = "comment"
+	// AttributionHeading precedes each included file with a heading naming its origin.
+	AttributionHeading AttributionStyle = "heading"
+)
+
+// AttributionStyles lists the valid attribution styles.
+var AttributionStyles = []AttributionStyle{AttributionNone, AttributionComment, AttributionHeading}
+
+// LinkMode defines how the flatten and preserve strategies install the files of local sources.
+type LinkMode string
+
+const (
+	// LinkCopy copies the files.
+	LinkCopy LinkMode = "copy"
+	// LinkSymlink creates symbolic links to the files, relative to the output when possible.
+	LinkSymlink LinkMode = "symlink"
+	// LinkHardlink creates hard links to the files.
+	LinkHardlink LinkMode = "hardlink"
+)
+
+// LinkModes lists the valid link modes.
+var LinkModes = []LinkMode{LinkCopy, LinkSymlink, LinkHardlink}
+
+// ConcatOptions configures the output of the concat strategy.
+type ConcatOptions struct {
+	// Frontmatter is the policy for the frontmatter of included files, strip by default.
+	Frontmatter FrontmatterPolicy `yaml:"frontmatter,omitempty" jsonschema:"default=strip" description:"How the frontmatter of included files is handled: removed, or removed and merged into the output header"`
+	// MergeKeys lists the frontmatter keys merged into the output header by the merge policy.
+	// The first included file that defines a key wins.
+	MergeKeys []string `yaml:"mergeKeys,omitempty" description:"Frontmatter keys merged into the output header by the merge policy. The first included file that defines a key wins." examples:"[\"description\", \"applyTo\"]"`
+	// Attribution marks the source, path and revision of each included file, none by default.
+	Attribution AttributionStyle `yaml:"attribution,omitempty" jsonschema:"default=none" description:"Precede each included file with an HTML comment or a heading naming its source, path and locked revision"`
+	// Separator is a line written between included files, e.g. "---".
+	Separator string `yaml:"separator,omitempty" description:"Line written between included files" examples:"---"`
+	// TOC inserts a linked table of contents of the included headings after the output header.
+	TOC bool `yaml:"toc,omitempty" jsonschema:"default=false" description:"Insert a linked table of contents of the included headings after the output header"`
+	// TOCMaxDepth is the deepest heading level listed in the table of contents, DefaultTOCMaxDepth by default.
+	TOCMaxDepth int `yaml:"tocMaxDepth,omitempty" jsonschema:"minimum=1,maximum=6,default=3" description:"Deepest heading level listed in the table of contents"`
+	// HeadingLevel shifts the headings of each included file so its top-level headings have this level.
+	// Headings are kept unchanged by default.
+	HeadingLevel int `yaml:"headingLevel,omitempty" jsonschema:"minimum=1,maximum=6" description:"Shift the headings of each included file so its top-level headings have this level" examples:"2"`
+}
+
+const DefaultTOCMaxDepth = 3
+
 type Include struct {
 	Source string
 	File   string
 }
 
+// TargetOutput is an output of a target and the strategy that writes the included files to it.
+type TargetOutput struct {
+	Output       string         `yaml:"output,omitempty" description:"Output directory path or file path for concatenation. Required unless format is set." examples:"AGENTS.md|.github/instructions"`
+	StrategyType StrategyType   `yaml:"strategy,omitempty" description:"Installation strategy. Defaults to the strategy of the format, or to concat when the output is a .md file and to flatten otherwise."`
+	Format       Format         `yaml:"format,omitempty" description:"Agent to write instructions for, in its file layout and frontmatter dialect. Sets the default output and strategy." examples:"claude|cursor"`
+	Concat       *ConcatOptions `yaml:"concat,omitempty" description:"Options of the concat strategy, overriding the concat options of the target"`
+}
+
 type Target struct {
-	Name          string       `yaml:"name"`
-	Output        string       `yaml:"output"`
-	StrategyType  StrategyType `yaml:"strategy,omitempty"`
-	Include       []string     `yaml:"include"`
-	IncludeParsed []Include    `yaml:"-"`
+	Name         string       `yaml:"name" jsonschema:"required,minLength=1" description:"Target name" examples:"copilot-instructions|documentation|combined-prompts"`
+	Output       string       `yaml:"output,omitempty" description:"Output directory path or file path for concatenation. Required unless format is set." examples:"./.github/copilot-instructions.md|./output|./dist/instructions.txt|output/"`
+	StrategyType StrategyType `yaml:"strategy,omitempty" description:"Installation strategy. Defaults to the strategy of the format, or to concat when the output is a .md file and to flatten otherwise." examples:"flatten|preserve|concat|cursor-rules|copilot-instructions"`
+	// Format selects an agent profile, which provides the default output and strategy of the target.
+	Format        Format    `yaml:"format,omitempty" description:"Agent to write instructions for, in its file layout and frontmatter dialect. Sets the default output and strategy." examples:"claude|cursor"`
+	Include       []string  `yaml:"include" description:"List of files to include in this target. Use '@source-name/path' for other sources, or just 'path' for working_dir. Paths may contain wildcards." examples:"[\"instructions/*.md\", \"@org-prompts/prompts/code-review.md\"]"`
+	IncludeParsed []Include `yaml:"-"`
+	// Exclude lists patterns of files that are removed from the matches of Include.
+	Exclude       []string  `yaml:"exclude,omitempty" description:"List of files to remove from the files matched by include, in the same format as include" examples:"[\"instructions/draft-*.md\", \"@org-prompts/README.md\"]"`
+	ExcludeParsed []Include `yaml:"-"`
+	// Concat configures the output of the concat strategy.
+	Concat *ConcatOptions `yaml:"concat,omitempty" description:"Options of the concat strategy, also the default of every entry of outputs"`
+	// Outputs lists several outputs that the included files are written to, instead of a single Output.
+	Outputs []TargetOutput `yaml:"outputs,omitempty" description:"Outputs the included files are written to, each with its own strategy. Replaces output, strategy and format."`
+	// Link installs the files of local sources as links instead of copies, with the flatten and preserve strategies.
+	Link LinkMode `yaml:"link,omitempty" jsonschema:"default=copy" description:"Install the files of local sources as symbolic or hard links with the flatten and preserve strategies, so that edits are visible without reinstalling. Files of remote sources and rendered files are always copied."`
+	// Render runs every included file through text/template with the variables of the target.
+	Render bool `yaml:"render,omitempty" jsonschema:"default=false" description:"Render every included file as a Go text/template with the variables of the target"`
+	// Vars are template variables of the target. They override the variables of the configuration.
+	Vars         map[string]any `yaml:"vars,omitempty" description:"Template variables of the target, overriding the top-level vars" examples:"{\"ProjectName\": \"billing-service\", \"Language\": \"Go\"}"`
+	VarsResolved map[string]any `yaml:"-"`
+}
+
+// ResolvedOutputs returns the outputs of the target: the entries of Outputs, or the output of the target itself.
+// The profile of the format provides the default output and strategy, and the concat options of the target are
+// the default of every output.
+func (t *Target) ResolvedOutputs() []TargetOutput {
+	outputs := t.Outputs
+	if len(outputs) == 0 {
+		outputs = []TargetOutput{{Output: t.Output, StrategyType: t.StrategyType, Format: t.Format, Concat: t.Concat}}
+	}
+
+	resolved := make([]TargetOutput, len(outputs))
+	for i, output := range outputs {
+		if profile, ok := LookupAgentProfile(output.Format); ok {
+			if output.Output == "" {
+				output.Output = profile.Output
+			}
+			if output.StrategyType == "" {
+				output.StrategyType = profile.Strategy
+			}
+		}
+		if output.Concat == nil {
+			output.Concat = t.Concat
+		}
+		resolved[i] = output
+	}
+	return resolved
 }
 
 type Config struct {
-	Version int      `yaml:"version"`
-	Sources []Source `yaml:"sources"`
-	Targets []Target `yaml:"targets"`
+	// Extends is the path of a base configuration, local or "@source/path", that this configuration overrides.
+	Extends string   `yaml:"extends,omitempty" description:"Base configuration that this configuration extends: a path relative to this file or '@source-name/path' of a source defined here. Sources and targets are merged by name." examples:"../pim-base.yaml|@org/pim-base.yaml"`
+	Version int      `yaml:"version" jsonschema:"required,const=1" description:"Configuration schema version" examples:"1"`
+	Sources []Source `yaml:"sources" description:"List of sources to fetch files from"`
+	Targets []Target `yaml:"targets" description:"List of installation targets"`
+	// Vars are template variables shared by all targets.
+	Vars map[string]any `yaml:"vars,omitempty" description:"Template variables available to all targets that are rendered" examples:"{\"ProjectName\": \"billing-service\", \"Language\": \"Go\"}"`
 }
 
 func NewConfig() *Config {
@@ -52,14 +208,16 @@ func NewConfig() *Config {
 
 // LoadConfig loads configuration from the given path using the provided filesystem.
 func LoadConfig(fs afero.Fs, configPath string, workingDir string) (*Config, error) {
-	data, err := afero.ReadFile(fs, configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
+	return LoadConfigWithResolver(fs, configPath, workingDir, nil)
+}
 
-	cfg := NewConfig()
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+// LoadConfigWithResolver loads configuration like LoadConfig, merging the base configurations it extends.
+// Base configurations of sources ("@source/path") are located with resolve. Without a resolver, only
+// local directory sources can be extended from.
+func LoadConfigWithResolver(fs afero.Fs, configPath string, workingDir string, resolve SourceFileResolver) (*Config, error) {
+	cfg, err := loadExtended(fs, configPath, resolve, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := cfg.addWorkingDirSource(workingDir); err != nil {
@@ -98,22 +256,58 @@ func (c *Config) normalize() error {
 		return err
 	}
 	c.setDefaultSourceForIncludes()
+	c.resolveVars()
 	return nil
 }
 
+// resolveVars merges the variables of the configuration into the variables of every target.
+func (c *Config) resolveVars() {
+	for i := range c.Targets {
+		vars := make(map[string]any, len(c.Vars)+len(c.Targets[i].Vars))
+		maps.Copy(vars, c.Vars)
+		maps.Copy(vars, c.Targets[i].Vars)
+		c.Targets[i].VarsResolved = vars
+	}
+}
+
 func (c *Config) parseIncludes() error {
+	if errs := c.parseIncludeFields(); len(errs) > 0 {
+		return errs[0].err
+	}
+	return nil
+}
+
+// parseIncludeFields parses the includes and excludes of every target and returns all errors.
+// Entries that fail to parse are kept as an empty Include, so that indices match the configuration file.
+func (c *Config) parseIncludeFields() []fieldError {
+	var errs []fieldError
 	for i := range c.Targets {
-		var includes []Include
-		for _, includeStr := range c.Targets[i].Include {
+		target := &c.Targets[i]
+		target.IncludeParsed = nil
+		for j, includeStr := range target.Include {
 			include, err := ParseInclude(includeStr)
 			if err != nil {
-				return fmt.Errorf("failed to parse include in target '%s': %w", c.Targets[i].Name, err)
+				errs = append(errs, fieldError{
+					path: fmt.Sprintf("targets[%d].include[%d]", i, j),
+					err:  fmt.Errorf("failed to parse include in target '%s': %w", target.Name, err),
+				})
+			}
+			target.IncludeParsed = append(target.IncludeParsed, include)
+		}
+
+		target.ExcludeParsed = nil
+		for j, excludeStr := range target.Exclude {
+			exclude, err := ParseInclude(excludeStr)
+			if err != nil {
+				errs = append(errs, fieldError{
+					path: fmt.Sprintf("targets[%d].exclude[%d]", i, j),
+					err:  fmt.Errorf("failed to parse exclude in target '%s': %w", target.Name, err),
+				})
 			}
-			includes = append(includes, include)
+			target.ExcludeParsed = append(target.ExcludeParsed, exclude)
 		}
-		c.Targets[i].IncludeParsed = includes
 	}
-	return nil
+	return errs
 }
 
 func (c *Config) setDefaultSourceForIncludes() {
@@ -124,39 +318,187 @@ func (c *Config) setDefaultSourceForIncludes() {
 				include.Source = DefaultSourceName
 			}
 		}
+		for j := range c.Targets[i].ExcludeParsed {
+			exclude := &c.Targets[i].ExcludeParsed[j]
+			if exclude.Source == "" {
+				exclude.Source = DefaultSourceName
+			}
+		}
 	}
 }
 
+// fieldError is a validation error of the value at a path of the configuration, e.g. "targets[0].strategy".
+type fieldError struct {
+	path string
+	err  error
+}
+
 func (c *Config) Validate() error {
+	if errs := c.validateFields(nil); len(errs) > 0 {
+		return errs[0].err
+	}
+	return nil
+}
+
+// validateFields returns all validation errors of the configuration. Includes may also reference the
+// sources named in inherited, e.g. sources of a base configuration.
+func (c *Config) validateFields(inherited []string) []fieldError {
+	var errs []fieldError
+	addError := func(path string, err error) {
+		errs = append(errs, fieldError{path: path, err: err})
+	}
+
 	sourceNames := make(map[string]bool)
-	for _, source := range c.Sources {
+	for _, name := range inherited {
+		sourceNames[name] = true
+	}
+
+	definedNames := make(map[string]bool)
+	for i, source := range c.Sources {
+		path := fmt.Sprintf("sources[%d]", i)
 		if source.Name == "" {
-			return fmt.Errorf("source name cannot be empty")
+			addError(path+".name", fmt.Errorf("source name cannot be empty"))
 		}
 		if strings.Contains(source.Name, "/") {
-			return fmt.Errorf("source name '%s' cannot contain '/'", source.Name)
+			addError(path+".name", fmt.Errorf("source name '%s' cannot contain '/'", source.Name))
 		}
-		if sourceNames[source.Name] {
-			return fmt.Errorf("duplicate source name: %s", source.Name)
+		if definedNames[source.Name] {
+			addError(path+".name", fmt.Errorf("duplicate source name: %s", source.Name))
 		}
+		if source.Ref != "" && source.Version != "" {
+			addError(path+".version", fmt.Errorf("source '%s' cannot define both ref and version", source.Name))
+		}
+		if source.Version != "" {
+			if _, err := semver.NewConstraint(source.Version); err != nil {
+				addError(path+".version", fmt.Errorf("source '%s' has invalid version constraint '%s': %w", source.Name, source.Version, err))
+			}
+		}
+		definedNames[source.Name] = true
 		sourceNames[source.Name] = true
 	}
 
-	for _, target := range c.Targets {
-		if target.StrategyType != "" && target.StrategyType != StrategyFlatten && target.StrategyType != StrategyPreserve && target.StrategyType != StrategyConcat {
-			return fmt.Errorf("target '%s' has invalid strategy: %s (must be 'flatten', 'preserve', or 'concat')", target.Name, target.StrategyType)
+	for i, target := range c.Targets {
+		path := fmt.Sprintf("targets[%d]", i)
+		if err := target.Concat.validate(); err != nil {
+			addError(path+".concat", fmt.Errorf("target '%s' has invalid concat options: %w", target.Name, err))
 		}
 
-		for _, include := range target.IncludeParsed {
-			if !sourceNames[include.Source] {
-				return fmt.Errorf("target '%s' references unknown source: %s", target.Name, include.Source)
+		if target.Link != "" && !slices.Contains(LinkModes, target.Link) {
+			addError(path+".link", fmt.Errorf("target '%s' has invalid link mode: %s (must be %s)", target.Name, target.Link, quotedList(LinkModes)))
+		}
+
+		if len(target.Outputs) == 0 {
+			errs = append(errs, target.validateOutput(path, TargetOutput{
+				Output:       target.Output,
+				StrategyType: target.StrategyType,
+				Format:       target.Format,
+			})...)
+		} else if key := target.singleOutputKey(); key != "" {
+			addError(path+"."+key, fmt.Errorf("target '%s' cannot combine outputs with output, strategy or format", target.Name))
+		}
+		for j, output := range target.Outputs {
+			errs = append(errs, target.validateOutput(fmt.Sprintf("%s.outputs[%d]", path, j), output)...)
+		}
+
+		for j, include := range target.IncludeParsed {
+			// Includes that failed to parse have no source.
+			if include.Source != "" && !sourceNames[include.Source] {
+				addError(fmt.Sprintf("%s.include[%d]", path, j), fmt.Errorf("target '%s' references unknown source: %s", target.Name, include.Source))
+			}
+		}
+		for j, exclude := range target.ExcludeParsed {
+			if exclude.Source != "" && !sourceNames[exclude.Source] {
+				addError(fmt.Sprintf("%s.exclude[%d]", path, j), fmt.Errorf("target '%s' references unknown source: %s", target.Name, exclude.Source))
 			}
 		}
 	}
 
+	return errs
+}
+
+// singleOutputKey returns the first key of the single output form that the target sets, or "".
+func (t *Target) singleOutputKey() string {
+	switch {
+	case t.Output != "":
+		return "output"
+	case t.StrategyType != "":
+		return "strategy"
+	case t.Format != "":
+		return "format"
+	}
+	return ""
+}
+
+// validateOutput returns the validation errors of an output of the target at path.
+func (t *Target) validateOutput(path string, output TargetOutput) []fieldError {
+	var errs []fieldError
+	if output.Format != "" && !slices.Contains(Formats, output.Format) {
+		errs = append(errs, fieldError{path + ".format", fmt.Errorf("target '%s' has invalid format: %s (must be %s)", t.Name, output.Format, quotedList(Formats))})
+	} else if output.Output == "" && output.Format == "" {
+		errs = append(errs, fieldError{path + ".output", fmt.Errorf("target '%s' requires an output or a format", t.Name)})
+	}
+
+	if output.StrategyType != "" && !slices.Contains(StrategyTypes, output.StrategyType) {
+		errs = append(errs, fieldError{path + ".strategy", fmt.Errorf("target '%s' has invalid strategy: %s (must be %s)", t.Name, output.StrategyType, quotedList(StrategyTypes))})
+	}
+
+	if err := output.Concat.validate(); err != nil {
+		errs = append(errs, fieldError{path + ".concat", fmt.Errorf("target '%s' has invalid concat options: %w", t.Name, err)})
+	}
+	return errs
+}
+
+func (o *ConcatOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+
+	switch o.Frontmatter {
+	case "", FrontmatterStrip:
+		if len(o.MergeKeys) > 0 {
+			return fmt.Errorf("mergeKeys requires the 'merge' frontmatter policy")
+		}
+	case FrontmatterMerge:
+		if len(o.MergeKeys) == 0 {
+			return fmt.Errorf("the 'merge' frontmatter policy requires mergeKeys")
+		}
+	default:
+		return fmt.Errorf("invalid frontmatter policy: %s (must be 'strip' or 'merge')", o.Frontmatter)
+	}
+
+	switch o.Attribution {
+	case "", AttributionNone, AttributionComment, AttributionHeading:
+	default:
+		return fmt.Errorf("invalid attribution: %s (must be 'none', 'comment' or 'heading')", o.Attribution)
+	}
+
+	if o.TOCMaxDepth < 0 || o.TOCMaxDepth > 6 {
+		return fmt.Errorf("invalid tocMaxDepth: %d (must be between 1 and 6)", o.TOCMaxDepth)
+	}
+
+	if o.HeadingLevel < 0 || o.HeadingLevel > 6 {
+		return fmt.Errorf("invalid headingLevel: %d (must be between 1 and 6)", o.HeadingLevel)
+	}
+	// Attribution headings are written one level above headingLevel, which must leave room for them.
+	if o.Attribution == AttributionHeading && o.HeadingLevel == 1 {
+		return fmt.Errorf("attribution 'heading' requires a headingLevel of at least 2")
+	}
+
 	return nil
 }
 
+// quotedList returns the values quoted and separated by commas, e.g. "'a', 'b' or 'c'".
+func quotedList[T ~string](values []T) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + string(value) + "'"
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
 func ParseInclude(includeStr string) (Include, error) {
 	// if includeStr starts with @, it's structure is "@source/path"
 	if len(includeStr) > 0 && includeStr[0] == '@' {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -80,6 +81,128 @@ targets:
     output: ./output
     include:
       - "@nonexistent/file.txt"
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' references unknown source: nonexistent",
+		},
+		{
+			name: "valid concat frontmatter merge",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      frontmatter: merge
+      mergeKeys: [description, applyTo]
+`,
+			workingDir:  "/test/working/dir",
+			expectError: false,
+		},
+		{
+			name: "invalid concat frontmatter policy",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      frontmatter: keep
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: invalid frontmatter policy: keep (must be 'strip' or 'merge')",
+		},
+		{
+			name: "invalid concat attribution",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      attribution: footnote
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: invalid attribution: footnote (must be 'none', 'comment' or 'heading')",
+		},
+		{
+			name: "invalid concat toc depth",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      toc: true
+      tocMaxDepth: 7
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: invalid tocMaxDepth: 7 (must be between 1 and 6)",
+		},
+		{
+			name: "invalid concat heading level",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      headingLevel: 7
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: invalid headingLevel: 7 (must be between 1 and 6)",
+		},
+		{
+			name: "attribution heading at heading level 1",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      attribution: heading
+      headingLevel: 1
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: attribution 'heading' requires a headingLevel of at least 2",
+		},
+		{
+			name: "concat merge policy without keys",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    include:
+      - "*.md"
+    concat:
+      frontmatter: merge
+`,
+			workingDir:  "/test/working/dir",
+			expectError: true,
+			errorMsg:    "target 'target1' has invalid concat options: the 'merge' frontmatter policy requires mergeKeys",
+		},
+		{
+			name: "exclude with reference to non-existent source",
+			config: `version: 1
+targets:
+  - name: target1
+    output: ./output
+    include:
+      - "*.md"
+    exclude:
+      - "@nonexistent/README.md"
 `,
 			workingDir:  "/test/working/dir",
 			expectError: true,
@@ -199,6 +322,17 @@ func TestValidate(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "invalid link mode",
+			config: &Config{
+				Version: 1,
+				Targets: []Target{
+					{Name: "t1", Output: "/output", Link: "junction"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "target 't1' has invalid link mode: junction (must be 'copy', 'symlink' or 'hardlink')",
+		},
 		{
 			name: "missing source reference",
 			config: &Config{
@@ -219,6 +353,37 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "target 't1' references unknown source: unknown",
 		},
+		{
+			name: "ref and version on the same source",
+			config: &Config{
+				Version: 1,
+				Sources: []Source{
+					{Name: "s1", URL: "github.com/org/repo", Ref: "main", Version: "^1.2"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "source 's1' cannot define both ref and version",
+		},
+		{
+			name: "valid version constraint",
+			config: &Config{
+				Version: 1,
+				Sources: []Source{
+					{Name: "s1", URL: "github.com/org/repo", Version: ">= 1.2, < 2"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid version constraint",
+			config: &Config{
+				Version: 1,
+				Sources: []Source{
+					{Name: "s1", URL: "github.com/org/repo", Version: "latest"},
+				},
+			},
+			expectError: true,
+		},
 		{
 			name: "`/` in source name",
 			config: &Config{
@@ -307,6 +472,83 @@ func TestDefaultSourceForIncludes(t *testing.T) {
 	}
 }
 
+func TestParseExcludes(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	config := `version: 1
+sources:
+  - name: custom
+    url: /path/to/custom
+targets:
+  - name: target1
+    output: ./output
+    include:
+      - "instructions/*.md"
+      - "@custom/*.md"
+    exclude:
+      - "instructions/draft-*.md"
+      - "@custom/README.md"
+`
+	if err := afero.WriteFile(fs, "pim.yaml", []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Include{
+		{Source: DefaultSourceName, File: "instructions/draft-*.md"},
+		{Source: "custom", File: "README.md"},
+	}
+	if !reflect.DeepEqual(cfg.Targets[0].ExcludeParsed, expected) {
+		t.Errorf("expected excludes %v, got %v", expected, cfg.Targets[0].ExcludeParsed)
+	}
+}
+
+func TestResolveVars(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	config := `version: 1
+vars:
+  ProjectName: pim
+  Language: Go
+targets:
+  - name: target1
+    output: ./AGENTS.md
+    render: true
+    vars:
+      Language: Golang
+      Strict: true
+    include:
+      - "*.md"
+  - name: target2
+    output: ./output
+    include:
+      - "*.md"
+`
+	if err := afero.WriteFile(fs, "pim.yaml", []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []map[string]any{
+		{"ProjectName": "pim", "Language": "Golang", "Strict": true},
+		{"ProjectName": "pim", "Language": "Go"},
+	}
+	for i, vars := range expected {
+		if !reflect.DeepEqual(cfg.Targets[i].VarsResolved, vars) {
+			t.Errorf("expected vars of target %d %v, got %v", i, vars, cfg.Targets[i].VarsResolved)
+		}
+	}
+	if !cfg.Targets[0].Render || cfg.Targets[1].Render {
+		t.Errorf("expected only target1 to be rendered")
+	}
+}
+
 func TestInvalidStrategy(t *testing.T) {
 	cfg := &Config{
 		Version: 1,
@@ -330,7 +572,7 @@ func TestInvalidStrategy(t *testing.T) {
 		t.Error("expected error for invalid strategy")
 	}
 
-	expectedMsg := "target 't1' has invalid strategy: invalid (must be 'flatten', 'preserve', or 'concat')"
+	expectedMsg := "target 't1' has invalid strategy: invalid (must be 'flatten', 'preserve', 'concat', 'cursor-rules', 'copilot-instructions', 'windsurf-rules' or 'cline-rules')"
 	if err.Error() != expectedMsg {
 		t.Errorf("expected error %q, got %q", expectedMsg, err.Error())
 	}
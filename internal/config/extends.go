@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/afero"
+)
+
+// SourceFileResolver returns the local path of a file of a source, fetching the source when needed.
+// It is used to load base configurations that extends references as "@source/path".
+type SourceFileResolver func(source Source, file string) (string, error)
+
+// loadExtended reads the configuration file at path, interpolates it and merges it over the configuration
+// it extends, recursively. chain lists the files that extend the configuration, to detect cycles.
+func loadExtended(fs afero.Fs, path string, resolve SourceFileResolver, chain []string) (*Config, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path '%s': %w", path, err)
+	}
+	if slices.Contains(chain, absPath) {
+		return nil, fmt.Errorf("cyclic extends: %s", strings.Join(append(chain, absPath), " -> "))
+	}
+	chain = append(chain, absPath)
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := NewConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := cfg.interpolate(fs, path); err != nil {
+		return nil, err
+	}
+
+	if cfg.Extends == "" {
+		return cfg, nil
+	}
+
+	basePath, err := cfg.resolveExtends(path, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := loadExtended(fs, basePath, resolve, chain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base config '%s': %w", cfg.Extends, err)
+	}
+
+	return base.merge(cfg), nil
+}
+
+// resolveExtends returns the path of the base configuration. Local paths are relative to the directory of
+// the configuration file, "@source/path" references a file of one of the sources of the configuration.
+func (c *Config) resolveExtends(path string, resolve SourceFileResolver) (string, error) {
+	if !strings.HasPrefix(c.Extends, "@") {
+		if filepath.IsAbs(c.Extends) {
+			return c.Extends, nil
+		}
+		return filepath.Join(filepath.Dir(path), c.Extends), nil
+	}
+
+	ref, err := ParseInclude(c.Extends)
+	if err != nil {
+		return "", fmt.Errorf("invalid extends: %w", err)
+	}
+
+	index := slices.IndexFunc(c.Sources, func(source Source) bool {
+		return source.Name == ref.Source
+	})
+	if index < 0 {
+		return "", fmt.Errorf("extends references unknown source: %s", ref.Source)
+	}
+	source := c.Sources[index]
+
+	if resolve != nil {
+		return resolve(source, ref.File)
+	}
+	if info, err := os.Stat(source.URL); err == nil && info.IsDir() {
+		return filepath.Join(source.URL, ref.File), nil
+	}
+	return "", fmt.Errorf("extends references source '%s', which must be fetched to load it", source.Name)
+}
+
+// merge returns the configuration c with the override applied. Sources and targets of the override replace
+// those of c with the same name and are appended otherwise; vars are merged by name.
+func (c *Config) merge(override *Config) *Config {
+	merged := &Config{
+		Version: override.Version,
+		Sources: mergeByName(c.Sources, override.Sources, func(source Source) string { return source.Name }),
+		Targets: mergeByName(c.Targets, override.Targets, func(target Target) string { return target.Name }),
+	}
+
+	if len(c.Vars) > 0 || len(override.Vars) > 0 {
+		merged.Vars = make(map[string]any, len(c.Vars)+len(override.Vars))
+		maps.Copy(merged.Vars, c.Vars)
+		maps.Copy(merged.Vars, override.Vars)
+	}
+
+	return merged
+}
+
+// mergeByName replaces the items of base with the override items of the same name, keeping their position,
+// and appends the other override items.
+func mergeByName[T any](base, overrides []T, name func(T) string) []T {
+	merged := slices.Clone(base)
+	for _, override := range overrides {
+		index := slices.IndexFunc(merged, func(item T) bool {
+			return name(item) == name(override)
+		})
+		if index >= 0 {
+			merged[index] = override
+		} else {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoadConfigExtends(t *testing.T) {
+	files := map[string]string{
+		"org/pim-base.yaml": `version: 1
+vars:
+  Company: ACME
+  Language: Go
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+    ref: main
+targets:
+  - name: copilot
+    output: ./.github/copilot-instructions.md
+    include:
+      - "@org/copilot/*.md"
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - "@org/agents/*.md"
+`,
+		"repo/team.yaml": `extends: ../org/pim-base.yaml
+version: 1
+sources:
+  - name: team
+    url: https://github.com/acme/team-prompts.git
+targets:
+  - name: team
+    output: ./.github/instructions
+    include:
+      - "@team/*.md"
+`,
+		"repo/pim.yaml": `extends: team.yaml
+version: 1
+vars:
+  Language: Rust
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+    ref: v2
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - "@org/agents/*.md"
+      - "instructions/*.md"
+`,
+	}
+
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+
+	cfg, err := LoadConfig(fs, "repo/pim.yaml", "/test/workdir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedSources := []Source{
+		{Name: DefaultSourceName, URL: "/test/workdir"},
+		{Name: "org", URL: "https://github.com/acme/prompts.git", Ref: "v2"},
+		{Name: "team", URL: "https://github.com/acme/team-prompts.git"},
+	}
+	if !reflect.DeepEqual(cfg.Sources, expectedSources) {
+		t.Errorf("expected sources %v, got %v", expectedSources, cfg.Sources)
+	}
+
+	var targets []string
+	for _, target := range cfg.Targets {
+		targets = append(targets, target.Name+":"+strings.Join(target.Include, ","))
+	}
+	expectedTargets := []string{
+		"copilot:@org/copilot/*.md",
+		"agents:@org/agents/*.md,instructions/*.md",
+		"team:@team/*.md",
+	}
+	if !reflect.DeepEqual(targets, expectedTargets) {
+		t.Errorf("expected targets %v, got %v", expectedTargets, targets)
+	}
+
+	expectedVars := map[string]any{"Company": "ACME", "Language": "Rust"}
+	if !reflect.DeepEqual(cfg.Vars, expectedVars) {
+		t.Errorf("expected vars %v, got %v", expectedVars, cfg.Vars)
+	}
+	if cfg.Extends != "" {
+		t.Errorf("expected resolved config to have no extends, got %q", cfg.Extends)
+	}
+}
+
+func TestLoadConfigExtendsSource(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "cache/org/pim-base.yaml", []byte(`version: 1
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - "@org/agents/*.md"
+`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := afero.WriteFile(fs, "pim.yaml", []byte(`extends: "@org/pim-base.yaml"
+version: 1
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	var resolved []string
+	resolve := func(source Source, file string) (string, error) {
+		resolved = append(resolved, source.Name+"/"+file)
+		return filepath.Join("cache", source.Name, file), nil
+	}
+
+	cfg, err := LoadConfigWithResolver(fs, "pim.yaml", "/test/workdir", resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(resolved, []string{"org/pim-base.yaml"}) {
+		t.Errorf("expected base config to be resolved from source, got %v", resolved)
+	}
+	if len(cfg.Targets) != 1 || cfg.Targets[0].Name != "agents" {
+		t.Errorf("expected target of base config, got %v", cfg.Targets)
+	}
+}
+
+func TestLoadConfigExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		expectError string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"pim.yaml": "extends: a.yaml\nversion: 1\n",
+				"a.yaml":   "extends: b.yaml\nversion: 1\n",
+				"b.yaml":   "extends: pim.yaml\nversion: 1\n",
+			},
+			expectError: "cyclic extends: ",
+		},
+		{
+			name: "missing base config",
+			files: map[string]string{
+				"pim.yaml": "extends: missing.yaml\nversion: 1\n",
+			},
+			expectError: "failed to load base config 'missing.yaml': failed to read config file",
+		},
+		{
+			name: "unknown source",
+			files: map[string]string{
+				"pim.yaml": "extends: \"@org/pim-base.yaml\"\nversion: 1\n",
+			},
+			expectError: "extends references unknown source: org",
+		},
+		{
+			name: "remote source without resolver",
+			files: map[string]string{
+				"pim.yaml": "extends: \"@org/pim-base.yaml\"\nversion: 1\nsources:\n  - name: org\n    url: https://github.com/acme/prompts.git\n",
+			},
+			expectError: "extends references source 'org', which must be fetched to load it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tt.files {
+				if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write config: %v", err)
+				}
+			}
+
+			_, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
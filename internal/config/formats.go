@@ -0,0 +1,61 @@
+package config
+
+import "slices"
+
+// Format selects the agent that a target writes instructions for.
+type Format string
+
+const (
+	FormatClaude        Format = "claude"
+	FormatCodex         Format = "codex"
+	FormatGemini        Format = "gemini"
+	FormatCopilot       Format = "copilot"
+	FormatCopilotScoped Format = "copilot-scoped"
+	FormatCursor        Format = "cursor"
+	FormatWindsurf      Format = "windsurf"
+	FormatCline         Format = "cline"
+)
+
+// AgentProfile describes where an agent reads its instructions from and the strategy that writes them
+// in the layout and frontmatter dialect of the agent.
+type AgentProfile struct {
+	Format Format
+	// Name is the name of the agent.
+	Name string
+	// Output is the file or directory the agent reads instructions from.
+	Output string
+	// Strategy writes the included files to the output.
+	Strategy StrategyType
+}
+
+// AgentProfiles is the registry of the agents that targets can select with format.
+var AgentProfiles = []AgentProfile{
+	{Format: FormatClaude, Name: "Claude Code", Output: "CLAUDE.md", Strategy: StrategyConcat},
+	{Format: FormatCodex, Name: "Codex", Output: "AGENTS.md", Strategy: StrategyConcat},
+	{Format: FormatGemini, Name: "Gemini CLI", Output: "GEMINI.md", Strategy: StrategyConcat},
+	{Format: FormatCopilot, Name: "GitHub Copilot", Output: ".github/copilot-instructions.md", Strategy: StrategyConcat},
+	{Format: FormatCopilotScoped, Name: "GitHub Copilot", Output: ".github/instructions", Strategy: StrategyCopilotInstructions},
+	{Format: FormatCursor, Name: "Cursor", Output: ".cursor/rules", Strategy: StrategyCursorRules},
+	{Format: FormatWindsurf, Name: "Windsurf", Output: ".windsurf/rules", Strategy: StrategyWindsurfRules},
+	{Format: FormatCline, Name: "Cline", Output: ".clinerules", Strategy: StrategyClineRules},
+}
+
+// Formats lists the valid formats.
+var Formats = func() []Format {
+	formats := make([]Format, len(AgentProfiles))
+	for i, profile := range AgentProfiles {
+		formats[i] = profile.Format
+	}
+	return formats
+}()
+
+// LookupAgentProfile returns the profile of a format.
+func LookupAgentProfile(format Format) (AgentProfile, bool) {
+	index := slices.IndexFunc(AgentProfiles, func(profile AgentProfile) bool {
+		return profile.Format == format
+	})
+	if index < 0 {
+		return AgentProfile{}, false
+	}
+	return AgentProfiles[index], true
+}
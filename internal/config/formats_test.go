@@ -0,0 +1,144 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoadConfigFormats(t *testing.T) {
+	tests := []struct {
+		name             string
+		target           string
+		expectedOutput   string
+		expectedStrategy StrategyType
+		expectError      string
+	}{
+		{
+			name:             "defaults of the format",
+			target:           "format: claude",
+			expectedOutput:   "CLAUDE.md",
+			expectedStrategy: StrategyConcat,
+		},
+		{
+			name:             "rules directory",
+			target:           "format: windsurf",
+			expectedOutput:   ".windsurf/rules",
+			expectedStrategy: StrategyWindsurfRules,
+		},
+		{
+			name:             "explicit output and strategy",
+			target:           "format: cursor\n    output: ./rules\n    strategy: preserve",
+			expectedOutput:   "./rules",
+			expectedStrategy: StrategyPreserve,
+		},
+		{
+			name:        "invalid format",
+			target:      "format: emacs",
+			expectError: "target 'agents' has invalid format: emacs (must be 'claude', 'codex', 'gemini', 'copilot', 'copilot-scoped', 'cursor', 'windsurf' or 'cline')",
+		},
+		{
+			name:        "neither output nor format",
+			target:      "strategy: concat",
+			expectError: "target 'agents' requires an output or a format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			content := "version: 1\ntargets:\n  - name: agents\n    " + tt.target + "\n    include:\n      - \"*.md\"\n"
+			if err := afero.WriteFile(fs, "pim.yaml", []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output := cfg.Targets[0].ResolvedOutputs()[0]
+			if output.Output != tt.expectedOutput || output.StrategyType != tt.expectedStrategy {
+				t.Errorf("expected output %q with strategy %q, got %q with %q",
+					tt.expectedOutput, tt.expectedStrategy, output.Output, output.StrategyType)
+			}
+		})
+	}
+}
+
+func TestLoadConfigOutputs(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		expected    []TargetOutput
+		expectError string
+	}{
+		{
+			name:   "single output",
+			target: "output: ./AGENTS.md\n    concat:\n      toc: true",
+			expected: []TargetOutput{
+				{Output: "./AGENTS.md", Concat: &ConcatOptions{TOC: true}},
+			},
+		},
+		{
+			name: "outputs with formats and inherited concat options",
+			target: `concat:
+      toc: true
+    outputs:
+      - format: codex
+      - format: cursor
+      - output: ./docs
+        strategy: preserve
+        concat:
+          toc: false`,
+			expected: []TargetOutput{
+				{Output: "AGENTS.md", StrategyType: StrategyConcat, Format: FormatCodex, Concat: &ConcatOptions{TOC: true}},
+				{Output: ".cursor/rules", StrategyType: StrategyCursorRules, Format: FormatCursor, Concat: &ConcatOptions{TOC: true}},
+				{Output: "./docs", StrategyType: StrategyPreserve, Concat: &ConcatOptions{}},
+			},
+		},
+		{
+			name:        "outputs and output",
+			target:      "output: ./AGENTS.md\n    outputs:\n      - format: cursor",
+			expectError: "target 'agents' cannot combine outputs with output, strategy or format",
+		},
+		{
+			name:        "invalid output",
+			target:      "outputs:\n      - format: cursor\n      - strategy: merge",
+			expectError: "target 'agents' requires an output or a format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			content := "version: 1\ntargets:\n  - name: agents\n    " + tt.target + "\n    include:\n      - \"*.md\"\n"
+			if err := afero.WriteFile(fs, "pim.yaml", []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if outputs := cfg.Targets[0].ResolvedOutputs(); !reflect.DeepEqual(outputs, tt.expected) {
+				t.Errorf("expected outputs %+v, got %+v", tt.expected, outputs)
+			}
+		})
+	}
+}
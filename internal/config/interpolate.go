@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/afero"
+)
+
+// EnvFileName is the name of the optional file next to the configuration that defines variables for interpolation.
+const EnvFileName = ".env"
+
+// variablePattern matches "$$", "${VAR}" and "${VAR:-default}".
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolator replaces references to environment variables in strings. Variables of the process
+// environment take precedence over the variables of the env file.
+type interpolator struct {
+	envFile   map[string]string
+	undefined map[string]bool
+}
+
+func newInterpolator(fs afero.Fs, configPath string) (*interpolator, error) {
+	envFile, err := readEnvFile(fs, filepath.Join(filepath.Dir(configPath), EnvFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &interpolator{
+		envFile:   envFile,
+		undefined: make(map[string]bool),
+	}, nil
+}
+
+func (i *interpolator) lookup(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := i.envFile[name]
+	return value, ok
+}
+
+// expand replaces the variable references in s. "${VAR:-default}" uses default when VAR is unset or empty,
+// "$$" is a literal "$". Undefined variables without a default are recorded.
+func (i *interpolator) expand(s string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := variablePattern.FindStringSubmatch(match)
+		name, hasDefault, defaultValue := groups[1], groups[2] != "", groups[3]
+
+		value, ok := i.lookup(name)
+		switch {
+		case ok && (value != "" || !hasDefault):
+			return value
+		case hasDefault:
+			return defaultValue
+		default:
+			i.undefined[name] = true
+			return ""
+		}
+	})
+}
+
+// MarshalResolved returns the configuration as YAML, with the URLs of the sources as written in the configuration
+// file, see Source.RawURL, so that secrets read from the environment are not printed.
+func (c *Config) MarshalResolved() ([]byte, error) {
+	resolved := *c
+	resolved.Sources = make([]Source, len(c.Sources))
+	for index, source := range c.Sources {
+		source.URL = source.RawURL()
+		resolved.Sources[index] = source
+	}
+	return yaml.Marshal(&resolved)
+}
+
+// interpolate expands the variable references in the strings of the configuration file at path. The URLs of
+// the sources are also kept as written, see Source.RawURL.
+func (c *Config) interpolate(fs afero.Fs, path string) error {
+	interpolator, err := newInterpolator(fs, path)
+	if err != nil {
+		return err
+	}
+
+	rawURLs := make([]string, len(c.Sources))
+	for index, source := range c.Sources {
+		rawURLs[index] = source.URL
+	}
+
+	if err := interpolator.interpolate(c); err != nil {
+		return fmt.Errorf("failed to interpolate config file: %w", err)
+	}
+
+	for index := range c.Sources {
+		if c.Sources[index].URL != rawURLs[index] {
+			c.Sources[index].rawURL = rawURLs[index]
+		}
+	}
+	return nil
+}
+
+// interpolate expands the variable references in every string of v, which must be a pointer.
+func (i *interpolator) interpolate(v any) error {
+	i.walk(reflect.ValueOf(v))
+
+	if len(i.undefined) > 0 {
+		names := make([]string, 0, len(i.undefined))
+		for name := range i.undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func (i *interpolator) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			i.walk(v.Elem())
+		}
+	case reflect.Interface:
+		// Strings stored in an interface, e.g. the values of vars, are not addressable and are replaced.
+		if !v.IsNil() && v.Elem().Kind() == reflect.String && v.CanSet() {
+			v.Set(reflect.ValueOf(i.expand(v.Elem().String())))
+		} else if !v.IsNil() {
+			i.walk(v.Elem())
+		}
+	case reflect.Struct:
+		for index := range v.NumField() {
+			if v.Type().Field(index).IsExported() {
+				i.walk(v.Field(index))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for index := range v.Len() {
+			i.walk(v.Index(index))
+		}
+	case reflect.Map:
+		// Map values are not addressable, so every value is copied, expanded and stored again.
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			i.walk(value)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(i.expand(v.String()))
+		}
+	}
+}
+
+// readEnvFile parses the KEY=VALUE lines of an env file. A missing file defines no variables.
+func readEnvFile(fs afero.Fs, path string) (map[string]string, error) {
+	data, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid line %d in env file '%s': expected KEY=VALUE", lineNumber, path)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoadConfigInterpolation(t *testing.T) {
+	t.Setenv("PIM_TEST_TOKEN", "secret")
+	t.Setenv("PIM_TEST_EMPTY", "")
+	t.Setenv("PIM_TEST_OVERRIDDEN", "from-environment")
+
+	tests := []struct {
+		name        string
+		config      string
+		envFile     string
+		expectError string
+		check       func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "environment variables and defaults",
+			config: `version: 1
+sources:
+  - name: org
+    url: https://${PIM_TEST_TOKEN}@example.com/org/prompts.git
+    ref: ${PIM_TEST_REF:-main}
+  - name: local
+    url: ${PIM_TEST_EMPTY:-./prompts}
+targets:
+  - name: target1
+    output: ./out-$${literal}
+    include:
+      - "@org/*.md"
+`,
+			check: func(t *testing.T, cfg *Config) {
+				expected := []Source{
+					{Name: DefaultSourceName, URL: "/test/workdir"},
+					{Name: "org", URL: "https://secret@example.com/org/prompts.git", Ref: "main", rawURL: "https://${PIM_TEST_TOKEN}@example.com/org/prompts.git"},
+					{Name: "local", URL: "./prompts", rawURL: "${PIM_TEST_EMPTY:-./prompts}"},
+				}
+				if !reflect.DeepEqual(cfg.Sources, expected) {
+					t.Errorf("expected sources %v, got %v", expected, cfg.Sources)
+				}
+				if raw := cfg.Sources[1].RawURL(); raw != "https://${PIM_TEST_TOKEN}@example.com/org/prompts.git" {
+					t.Errorf("expected raw url of org to keep the variable, got %q", raw)
+				}
+				data, err := cfg.MarshalResolved()
+				if err != nil {
+					t.Fatalf("failed to marshal config: %v", err)
+				}
+				if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "${PIM_TEST_TOKEN}") {
+					t.Errorf("expected resolved config to keep the token variable, got:\n%s", data)
+				}
+				if cfg.Sources[1].URL != "https://secret@example.com/org/prompts.git" {
+					t.Errorf("expected marshalling to leave the config unchanged, got %q", cfg.Sources[1].URL)
+				}
+				if cfg.Targets[0].Output != "./out-${literal}" {
+					t.Errorf("expected escaped output, got %q", cfg.Targets[0].Output)
+				}
+			},
+		},
+		{
+			name: "env file",
+			config: `version: 1
+vars:
+  Owner: ${PIM_TEST_OWNER}
+  Tags: ["${PIM_TEST_OVERRIDDEN}"]
+targets:
+  - name: target1
+    output: ${PIM_TEST_OUTPUT}
+    include:
+      - "*.md"
+`,
+			envFile: `# local settings
+PIM_TEST_OWNER="platform team"
+export PIM_TEST_OUTPUT=./generated
+PIM_TEST_OVERRIDDEN=from-env-file
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Targets[0].Output != "./generated" {
+					t.Errorf("expected output from env file, got %q", cfg.Targets[0].Output)
+				}
+				expected := map[string]any{"Owner": "platform team", "Tags": []any{"from-environment"}}
+				if !reflect.DeepEqual(cfg.Vars, expected) {
+					t.Errorf("expected vars %v, got %v", expected, cfg.Vars)
+				}
+			},
+		},
+		{
+			name: "undefined variables",
+			config: `version: 1
+sources:
+  - name: org
+    url: https://${PIM_TEST_UNDEFINED_B}@example.com/${PIM_TEST_UNDEFINED_A}.git
+targets:
+  - name: ${PIM_TEST_UNDEFINED_B}
+    output: ${PIM_TEST_UNDEFINED_C:-./out}
+    include:
+      - "@org/*.md"
+`,
+			expectError: "failed to interpolate config file: undefined variables: PIM_TEST_UNDEFINED_A, PIM_TEST_UNDEFINED_B",
+		},
+		{
+			name: "invalid env file",
+			config: `version: 1
+targets: []
+`,
+			envFile:     "PIM_TEST_OWNER\n",
+			expectError: "invalid line 1 in env file '.env': expected KEY=VALUE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, "pim.yaml", []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed to write test config: %v", err)
+			}
+			if tt.envFile != "" {
+				if err := afero.WriteFile(fs, EnvFileName, []byte(tt.envFile), 0644); err != nil {
+					t.Fatalf("failed to write env file: %v", err)
+				}
+			}
+
+			cfg, err := LoadConfig(fs, "pim.yaml", "/test/workdir")
+			if tt.expectError != "" {
+				if err == nil || err.Error() != tt.expectError {
+					t.Fatalf("expected error %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tt.check(t, cfg)
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// jsonSchema is the subset of JSON Schema that describes the configuration file.
+type jsonSchema struct {
+	Schema               string            `json:"$schema,omitempty"`
+	Title                string            `json:"title,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Type                 string            `json:"type,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+	Properties           *schemaProperties `json:"properties,omitempty"`
+	AdditionalProperties *bool             `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema       `json:"items,omitempty"`
+	Enum                 []any             `json:"enum,omitempty"`
+	Const                any               `json:"const,omitempty"`
+	Default              any               `json:"default,omitempty"`
+	MinLength            *int              `json:"minLength,omitempty"`
+	Minimum              *float64          `json:"minimum,omitempty"`
+	Maximum              *float64          `json:"maximum,omitempty"`
+	Examples             []any             `json:"examples,omitempty"`
+}
+
+// schemaProperties are the properties of an object schema, marshaled in the order of the struct fields.
+type schemaProperties struct {
+	names   []string
+	schemas map[string]*jsonSchema
+}
+
+func (p *schemaProperties) add(name string, schema *jsonSchema) {
+	if p.schemas == nil {
+		p.schemas = make(map[string]*jsonSchema)
+	}
+	p.names = append(p.names, name)
+	p.schemas[name] = schema
+}
+
+func (p *schemaProperties) get(name string) (*jsonSchema, bool) {
+	if p == nil {
+		return nil, false
+	}
+	schema, ok := p.schemas[name]
+	return schema, ok
+}
+
+func (p *schemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for index, name := range p.names {
+		if index > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.schemas[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// schemaEnums lists the valid values of the string types of the configuration.
+var schemaEnums = map[reflect.Type][]any{
+	reflect.TypeFor[StrategyType]():      enumOf(StrategyTypes),
+	reflect.TypeFor[Format]():            enumOf(Formats),
+	reflect.TypeFor[FrontmatterPolicy](): enumOf(FrontmatterPolicies),
+	reflect.TypeFor[AttributionStyle]():  enumOf(AttributionStyles),
+	reflect.TypeFor[LinkMode]():          enumOf(LinkModes),
+}
+
+func enumOf[T ~string](values []T) []any {
+	enum := make([]any, len(values))
+	for index, value := range values {
+		enum[index] = string(value)
+	}
+	return enum
+}
+
+// Schema returns the JSON schema of the configuration file, derived from the struct tags of Config.
+func Schema() ([]byte, error) {
+	schema, err := configSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func configSchema() (*jsonSchema, error) {
+	schema, err := typeSchema(reflect.TypeFor[Config]())
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "PIM Configuration Schema"
+	schema.Description = "Schema for PIM (Prompt Instruction Manager) configuration files"
+	return schema, nil
+}
+
+// typeSchema returns the schema of a configuration type. Struct fields are described by fieldSchema.
+func typeSchema(t reflect.Type) (*jsonSchema, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	schema := &jsonSchema{Enum: schemaEnums[t]}
+	switch t.Kind() {
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Int:
+		schema.Type = "integer"
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema.Type = "array"
+		schema.Items = items
+	case reflect.Map:
+		schema.Type = "object"
+		schema.AdditionalProperties = new(bool)
+		*schema.AdditionalProperties = true
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Title = t.Name()
+		schema.Properties = &schemaProperties{}
+		schema.AdditionalProperties = new(bool)
+		for index := range t.NumField() {
+			field := t.Field(index)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+
+			property, required, err := fieldSchema(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema of field %s.%s: %w", t.Name(), field.Name, err)
+			}
+			schema.Properties.add(name, property)
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", t)
+	}
+	return schema, nil
+}
+
+// fieldSchema returns the schema of a struct field described by its description, examples and jsonschema tags,
+// and whether the field is required.
+func fieldSchema(field reflect.StructField) (*jsonSchema, bool, error) {
+	schema, err := typeSchema(field.Type)
+	if err != nil {
+		return nil, false, err
+	}
+	schema.Title = ""
+	schema.Description = field.Tag.Get("description")
+
+	if examples := field.Tag.Get("examples"); examples != "" {
+		for _, example := range strings.Split(examples, "|") {
+			value, err := parseSchemaValue(schema.Type, example)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid example '%s': %w", example, err)
+			}
+			schema.Examples = append(schema.Examples, value)
+		}
+	}
+
+	required := false
+	for _, keyword := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+		key, value, _ := strings.Cut(keyword, "=")
+		switch key {
+		case "":
+		case "required":
+			required = true
+		case "const", "default":
+			parsed, err := parseSchemaValue(schema.Type, value)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid %s '%s': %w", key, value, err)
+			}
+			if key == "const" {
+				schema.Const = parsed
+			} else {
+				schema.Default = parsed
+			}
+		case "minLength":
+			minLength, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid minLength '%s': %w", value, err)
+			}
+			schema.MinLength = &minLength
+		case "minimum", "maximum":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid %s '%s': %w", key, value, err)
+			}
+			if key == "minimum" {
+				schema.Minimum = &number
+			} else {
+				schema.Maximum = &number
+			}
+		default:
+			return nil, false, fmt.Errorf("unknown jsonschema keyword: %s", key)
+		}
+	}
+
+	return schema, required, nil
+}
+
+// parseSchemaValue parses a value given in a struct tag: strings are taken literally, other types are JSON.
+func parseSchemaValue(schemaType string, value string) (any, error) {
+	if schemaType == "string" {
+		return value, nil
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// schemaValidator checks a YAML document against a schema and records the position of every value it visits,
+// so that later checks can report problems at the position of a path.
+type schemaValidator struct {
+	problems  []Problem
+	positions map[string]Problem
+	// invalid holds the paths that problems were reported at.
+	invalid []string
+}
+
+func newSchemaValidator() *schemaValidator {
+	return &schemaValidator{positions: make(map[string]Problem)}
+}
+
+func (v *schemaValidator) report(node ast.Node, path string, format string, args ...any) {
+	problem := positionOf(node)
+	problem.Message = fmt.Sprintf(format, args...)
+	if path != "" {
+		problem.Message = path + ": " + problem.Message
+	}
+	v.problems = append(v.problems, problem)
+	v.invalid = append(v.invalid, path)
+}
+
+// reported returns whether a problem was reported at path or at one of its children.
+func (v *schemaValidator) reported(path string) bool {
+	return slices.ContainsFunc(v.invalid, func(invalid string) bool {
+		return invalid == path || strings.HasPrefix(invalid, path+".") || strings.HasPrefix(invalid, path+"[")
+	})
+}
+
+// validate checks the node at path against the schema.
+func (v *schemaValidator) validate(node ast.Node, schema *jsonSchema, path string) {
+	node = unwrapNode(node)
+	if _, ok := node.(*ast.AliasNode); ok {
+		// The anchored value is validated where it is defined.
+		return
+	}
+	v.positions[path] = positionOf(node)
+
+	kind := nodeKind(node)
+	if schema.Type != "" && kind != schema.Type && (schema.Type != "number" || kind != "integer") {
+		v.report(node, path, "expected %s, got %s", schema.Type, kind)
+		return
+	}
+
+	if value, ok := scalarValue(node); ok {
+		v.validateScalar(node, value, schema, path)
+	}
+
+	switch node := node.(type) {
+	case *ast.MappingNode:
+		v.validateMapping(node, schema, path)
+	case *ast.SequenceNode:
+		if schema.Items != nil {
+			for index, item := range node.Values {
+				v.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, index))
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateScalar(node ast.Node, value any, schema *jsonSchema, path string) {
+	if schema.Const != nil && fmt.Sprint(value) != fmt.Sprint(schema.Const) {
+		v.report(node, path, "must be %v", schema.Const)
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(allowed any) bool {
+		return fmt.Sprint(value) == fmt.Sprint(allowed)
+	}) {
+		allowed := make([]string, len(schema.Enum))
+		for index, value := range schema.Enum {
+			allowed[index] = fmt.Sprint(value)
+		}
+		v.report(node, path, "must be one of: %s", strings.Join(allowed, ", "))
+	}
+
+	switch value := value.(type) {
+	case string:
+		if schema.MinLength != nil && len([]rune(value)) < *schema.MinLength {
+			v.report(node, path, "must be at least %d characters long", *schema.MinLength)
+		}
+	case int64, uint64, float64:
+		number := toFloat(value)
+		if schema.Minimum != nil && number < *schema.Minimum {
+			v.report(node, path, "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			v.report(node, path, "must be at most %v", *schema.Maximum)
+		}
+	}
+}
+
+func (v *schemaValidator) validateMapping(node *ast.MappingNode, schema *jsonSchema, path string) {
+	keys := make(map[string]bool, len(node.Values))
+	for _, entry := range node.Values {
+		key := mappingKey(entry)
+		if keys[key] {
+			v.report(entry.Key, path, "duplicate key '%s'", key)
+			continue
+		}
+		keys[key] = true
+
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		if property, ok := schema.Properties.get(key); ok {
+			v.validate(entry.Value, property, childPath)
+		} else if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+			v.report(entry.Key, path, "unknown key '%s'", key)
+		}
+	}
+
+	for _, required := range schema.Required {
+		if !keys[required] {
+			v.report(node, path, "missing required key '%s'", required)
+		}
+	}
+}
+
+// positionAt returns the position of the value at path, or of its closest parent that was visited.
+func (v *schemaValidator) positionAt(path string) Problem {
+	for path != "" {
+		if problem, ok := v.positions[path]; ok {
+			return problem
+		}
+		path = path[:max(strings.LastIndexAny(path, ".["), 0)]
+	}
+	return v.positions[""]
+}
+
+func positionOf(node ast.Node) Problem {
+	// The token of a block mapping is the ':' of its first entry.
+	if mapping, ok := node.(*ast.MappingNode); ok && !mapping.IsFlowStyle && len(mapping.Values) > 0 {
+		node = mapping.Values[0].Key
+	}
+	if node == nil || node.GetToken() == nil {
+		return Problem{}
+	}
+	position := node.GetToken().Position
+	return Problem{Line: position.Line, Column: position.Column}
+}
+
+// unwrapNode returns the value of anchors, tags and explicit mapping keys.
+func unwrapNode(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		case *ast.MappingKeyNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
+
+// nodeKind returns the JSON Schema type of a node.
+func nodeKind(node ast.Node) string {
+	switch node.(type) {
+	case *ast.MappingNode:
+		return "object"
+	case *ast.SequenceNode:
+		return "array"
+	case *ast.StringNode, *ast.LiteralNode:
+		return "string"
+	case *ast.IntegerNode:
+		return "integer"
+	case *ast.FloatNode, *ast.InfinityNode, *ast.NanNode:
+		return "number"
+	case *ast.BoolNode:
+		return "boolean"
+	case *ast.NullNode, nil:
+		return "null"
+	default:
+		return node.Type().String()
+	}
+}
+
+func scalarValue(node ast.Node) (any, bool) {
+	switch node := node.(type) {
+	case *ast.LiteralNode:
+		return node.Value.Value, true
+	case ast.ScalarNode:
+		return node.GetValue(), true
+	default:
+		return nil, false
+	}
+}
+
+func mappingKey(entry *ast.MappingValueNode) string {
+	if value, ok := scalarValue(unwrapNode(entry.Key)); ok {
+		return fmt.Sprint(value)
+	}
+	return entry.Key.String()
+}
+
+func toFloat(value any) float64 {
+	switch value := value.(type) {
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float64:
+		return value
+	default:
+		return 0
+	}
+}
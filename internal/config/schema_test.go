@@ -0,0 +1,22 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSchemaIsUpToDate(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+
+	committed, err := os.ReadFile("../../schema.json")
+	if err != nil {
+		t.Fatalf("failed to read schema.json: %v", err)
+	}
+
+	if string(committed) != string(schema) {
+		t.Errorf("schema.json is out of date with the configuration types, run 'make schema' to regenerate it")
+	}
+}
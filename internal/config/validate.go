@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/spf13/afero"
+)
+
+// Problem is an issue found in a configuration file. Line and Column are 1-based, or 0 when the problem
+// has no position in the file.
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidateFile checks the configuration file at path against the JSON schema returned by Schema and the rules
+// of Validate and returns all problems found, sorted by position. Unknown keys are reported, and includes
+// without wildcards of local sources must exist. Base configurations referenced by extends are loaded with
+// resolve to know their sources. The error is only set when the file cannot be read.
+func ValidateFile(fs afero.Fs, path string, workingDir string, resolve SourceFileResolver) ([]Problem, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	rootSchema, err := configSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return []Problem{syntaxProblem(err)}, nil
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return []Problem{{Line: 1, Column: 1, Message: "configuration is empty"}}, nil
+	}
+
+	validator := newSchemaValidator()
+	validator.validate(file.Docs[0].Body, rootSchema, "")
+	problems := validator.problems
+
+	// Values of the wrong type are already reported by the schema and prevent further checks.
+	cfg := NewConfig()
+	if err := yaml.Unmarshal(data, cfg); err == nil {
+		for _, fieldErr := range cfg.validateFile(fs, path, workingDir, resolve) {
+			// Values that violate the schema are not reported twice.
+			if fieldErr.path != "" && validator.reported(fieldErr.path) {
+				continue
+			}
+			problem := validator.positionAt(fieldErr.path)
+			problem.Message = fieldErr.err.Error()
+			problems = append(problems, problem)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems, nil
+}
+
+// validateFile returns the validation errors of a configuration file that was not normalized.
+func (c *Config) validateFile(fs afero.Fs, path string, workingDir string, resolve SourceFileResolver) []fieldError {
+	if err := c.interpolate(fs, path); err != nil {
+		return []fieldError{{err: err}}
+	}
+
+	// Sources are resolved like LoadConfig does: the working directory, then inherited and own sources.
+	sources := map[string]string{DefaultSourceName: workingDir}
+	if c.Extends != "" {
+		base, err := loadExtended(fs, path, resolve, nil)
+		if err != nil {
+			return []fieldError{{path: "extends", err: err}}
+		}
+		for _, source := range base.Sources {
+			sources[source.Name] = source.URL
+		}
+	}
+	for _, source := range c.Sources {
+		sources[source.Name] = source.URL
+	}
+
+	inherited := make([]string, 0, len(sources))
+	for name := range sources {
+		inherited = append(inherited, name)
+	}
+
+	errs := c.parseIncludeFields()
+	errs = append(errs, c.validateFields(inherited)...)
+	errs = append(errs, c.validateIncludesExist(fs, sources)...)
+	return errs
+}
+
+// validateIncludesExist checks that includes without wildcards exist in local sources.
+// Remote sources are not fetched and their includes are not checked.
+func (c *Config) validateIncludesExist(fs afero.Fs, sources map[string]string) []fieldError {
+	var errs []fieldError
+	for i, target := range c.Targets {
+		for j, include := range target.IncludeParsed {
+			dir, ok := sources[include.Source]
+			if !ok || include.File == "" || strings.ContainsAny(include.File, "*?[{") {
+				continue
+			}
+			if info, err := fs.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+
+			if _, err := fs.Stat(filepath.Join(dir, include.File)); err != nil {
+				errs = append(errs, fieldError{
+					path: fmt.Sprintf("targets[%d].include[%d]", i, j),
+					err:  fmt.Errorf("target '%s' includes '%s', which does not exist", target.Name, target.Include[j]),
+				})
+			}
+		}
+	}
+	return errs
+}
+
+func syntaxProblem(err error) Problem {
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+		position := yamlErr.GetToken().Position
+		return Problem{Line: position.Line, Column: position.Column, Message: yamlErr.GetMessage()}
+	}
+	return Problem{Message: err.Error()}
+}
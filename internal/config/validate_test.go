@@ -0,0 +1,146 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		files    map[string]string
+		expected []string
+	}{
+		{
+			name: "valid",
+			config: `version: 1
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - instructions/base.md
+      - instructions/*.md
+      - "@org/missing.md"
+`,
+			files: map[string]string{"/test/workdir/instructions/base.md": "# Base"},
+		},
+		{
+			name: "all problems with positions",
+			config: `version: 2
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+    ref: main
+    version: ^1.0
+    branch: main
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    strategy: merge
+    include:
+      - instructions/missing.md
+      - "@other/*.md"
+  - name: docs
+    include:
+      - instructions/*.md
+    concat:
+      tocMaxDepth: 9
+`,
+			expected: []string{
+				"1:10: version: must be 1",
+				"6:14: source 'org' cannot define both ref and version",
+				"7:5: sources[0]: unknown key 'branch'",
+				"11:15: targets[0].strategy: must be one of: flatten, preserve, concat, cursor-rules, copilot-instructions, windsurf-rules, cline-rules",
+				"13:9: target 'agents' includes 'instructions/missing.md', which does not exist",
+				"14:9: target 'agents' references unknown source: other",
+				"15:5: target 'docs' requires an output or a format",
+				"19:20: targets[1].concat.tocMaxDepth: must be at most 6",
+			},
+		},
+		{
+			name: "wrong types",
+			config: `version: "1"
+targets:
+  - name: docs
+    output: ./docs
+    include: instructions/*.md
+    render: yes please
+`,
+			expected: []string{
+				"1:10: version: expected integer, got string",
+				"5:14: targets[0].include: expected array, got string",
+				"6:13: targets[0].render: expected boolean, got string",
+			},
+		},
+		{
+			name:     "syntax error",
+			config:   "version: 1\ntargets: [\n",
+			expected: []string{"2:10: sequence end token ']' not found"},
+		},
+		{
+			name:     "empty",
+			config:   "# nothing yet\n",
+			expected: []string{"1:1: configuration is empty"},
+		},
+		{
+			name: "invalid include",
+			config: `version: 1
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - "@org"
+`,
+			expected: []string{"6:9: failed to parse include in target 'agents': invalid include format: @org"},
+		},
+		{
+			name: "sources of base config",
+			config: `extends: base.yaml
+version: 1
+targets:
+  - name: agents
+    output: ./AGENTS.md
+    include:
+      - "@org/*.md"
+`,
+			files: map[string]string{"/test/workdir/base.yaml": `version: 1
+sources:
+  - name: org
+    url: https://github.com/acme/prompts.git
+`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, "/test/workdir/pim.yaml", []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			for path, content := range tt.files {
+				if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			problems, err := ValidateFile(fs, "/test/workdir/pim.yaml", "/test/workdir", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.String())
+			}
+			if !reflect.DeepEqual(messages, tt.expected) {
+				t.Errorf("expected problems:\n%v\ngot:\n%v", tt.expected, messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+type DriftKind string
+
+const (
+	// DriftMissing marks a file that would be created by an installation.
+	DriftMissing DriftKind = "missing"
+	// DriftStale marks a file that would be updated or deleted by an installation.
+	DriftStale DriftKind = "stale"
+	// DriftModified marks an installed file that was edited after the last installation.
+	DriftModified DriftKind = "modified"
+)
+
+// Drift describes an installed file that differs from what an installation would produce.
+type Drift struct {
+	Path string
+	Kind DriftKind
+}
+
+// Check runs the installation against an in-memory overlay of fs and reports every installed
+// file that differs from what the installation would produce. Nothing is written to fs.
+func Check(fs afero.Fs, options *Options) ([]Drift, error) {
+	plan, err := PlanInstall(fs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	installedHashes := make(map[string]string)
+	if options.LockPath != "" {
+		locked, err := lockfile.Load(fs, options.LockPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range locked.Targets {
+			for path, hash := range target.Files {
+				installedHashes[path] = hash
+			}
+		}
+	}
+
+	var drifts []Drift
+	for _, target := range plan.Targets {
+		for _, change := range target.Changes {
+			drift := Drift{Path: change.Path, Kind: DriftStale}
+			switch change.Kind {
+			case utils.ChangeCreated:
+				drift.Kind = DriftMissing
+			case utils.ChangeUpdated:
+				if expected, ok := installedHashes[filepath.ToSlash(change.Path)]; ok {
+					hash, err := utils.HashFile(fs, change.Path)
+					if err != nil {
+						return nil, fmt.Errorf("failed to hash installed file '%s': %w", change.Path, err)
+					}
+					if hash != expected {
+						drift.Kind = DriftModified
+					}
+				}
+			}
+
+			drifts = append(drifts, drift)
+		}
+	}
+
+	return drifts, nil
+}
@@ -0,0 +1,66 @@
+package installer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestCheck(t *testing.T) {
+	fs := afero.NewOsFs()
+	options, dir := newLocalInstallOptions(t, fs, map[string]string{"a.md": "A", "b.md": "B"})
+	sourceDir := filepath.Join(dir, "source")
+	outputDir := filepath.Join(dir, "out")
+
+	drifts, err := Check(fs, options)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	expected := []Drift{
+		{Path: filepath.Join(outputDir, "a.md"), Kind: DriftMissing},
+		{Path: filepath.Join(outputDir, "b.md"), Kind: DriftMissing},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected drifts %v before install, got %v", expected, drifts)
+	}
+	if exists, _ := afero.Exists(fs, outputDir); exists {
+		t.Fatal("expected check not to write the output")
+	}
+
+	if err := NewInstaller(fs).Install(options); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	drifts, err = Check(fs, options)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift after install, got %v", drifts)
+	}
+
+	if err := afero.WriteFile(fs, filepath.Join(sourceDir, "a.md"), []byte("A2"), 0644); err != nil {
+		t.Fatalf("failed to update source file: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(outputDir, "b.md"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("failed to edit output file: %v", err)
+	}
+	// Files not written by PIM are never touched, so they are not reported.
+	if err := afero.WriteFile(fs, filepath.Join(outputDir, "extra.md"), []byte("extra"), 0644); err != nil {
+		t.Fatalf("failed to write extra file: %v", err)
+	}
+
+	drifts, err = Check(fs, options)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	expected = []Drift{
+		{Path: filepath.Join(outputDir, "a.md"), Kind: DriftStale},
+		{Path: filepath.Join(outputDir, "b.md"), Kind: DriftModified},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected drifts %v, got %v", expected, drifts)
+	}
+}
@@ -0,0 +1,225 @@
+package installer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/hashicorp/go-getter"
+)
+
+const gitForcedGetter = "git::"
+
+var commitHashRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// gitRemote is a go-getter source URL that is backed by a git repository.
+type gitRemote struct {
+	// repo is the plain repository URL, usable with "git ls-remote".
+	repo   string
+	subdir string
+	query  url.Values
+}
+
+// detectGitRemote expands the source URL with go-getter detectors and
+// reports whether it will be fetched with the git getter.
+func detectGitRemote(sourceURL string) (*gitRemote, bool, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	detected, err := getter.Detect(sourceURL, pwd, getter.Detectors)
+	if err != nil {
+		return nil, false, err
+	}
+
+	detected, ok := strings.CutPrefix(detected, gitForcedGetter)
+	if !ok {
+		return nil, false, nil
+	}
+
+	detected, subdir := getter.SourceDirSubdir(detected)
+
+	repo, rawQuery, _ := strings.Cut(detected, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse query of '%s': %w", sourceURL, err)
+	}
+
+	return &gitRemote{
+		repo:   repo,
+		subdir: subdir,
+		query:  query,
+	}, true, nil
+}
+
+// ref returns the ref requested in the source URL, if any.
+func (r *gitRemote) ref() string {
+	return r.query.Get("ref")
+}
+
+// getterURL returns the go-getter URL that fetches the repository at the given commit.
+func (r *gitRemote) getterURL(commit string) string {
+	query := url.Values{}
+	for key, values := range r.query {
+		query[key] = values
+	}
+	query.Set("ref", commit)
+	// Shallow clones can only check out named refs, not commits.
+	query.Del("depth")
+
+	result := gitForcedGetter + r.repo
+	if r.subdir != "" {
+		result += "//" + r.subdir
+	}
+
+	return result + "?" + query.Encode()
+}
+
+// cacheURL identifies the fetched content independently of the requested ref and credentials, so a commit
+// is cached once no matter which branch, tag or version constraint resolved to it.
+func (r *gitRemote) cacheURL() string {
+	if r.subdir != "" {
+		return r.displayRepo() + "//" + r.subdir
+	}
+	return r.displayRepo()
+}
+
+// displayRepo returns the repository URL without credentials.
+func (r *gitRemote) displayRepo() string {
+	return strings.Replace(r.repo, urlCredentials(r.repo), "", 1)
+}
+
+// urlCredentials returns the user information of the URL including the trailing "@", e.g. "token@", or "" when the
+// URL has none.
+func urlCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	return u.User.String() + "@"
+}
+
+// resolveCommit resolves the ref (or the remote HEAD when empty) to a commit hash.
+func (r *gitRemote) resolveCommit(ref string) (string, error) {
+	if commitHashRegexp.MatchString(ref) {
+		return ref, nil
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	out, err := r.lsRemote("--", r.repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	commit, ok := findRefCommit(out, ref)
+	if !ok {
+		return "", fmt.Errorf("ref '%s' not found in '%s' (commits must be given as full 40-character hashes)", ref, r.displayRepo())
+	}
+
+	return commit, nil
+}
+
+// resolveVersion resolves the highest tag matching the semver constraint to a commit hash.
+func (r *gitRemote) resolveVersion(constraint string) (commit string, tag string, err error) {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid version constraint '%s': %w", constraint, err)
+	}
+
+	out, err := r.lsRemote("--tags", "--", r.repo)
+	if err != nil {
+		return "", "", err
+	}
+
+	commit, tag, ok := findVersionCommit(out, constraints)
+	if !ok {
+		return "", "", fmt.Errorf("no tag in '%s' matches version '%s'", r.displayRepo(), constraint)
+	}
+
+	return commit, tag, nil
+}
+
+func (r *gitRemote) lsRemote(args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"ls-remote"}, args...)...).Output()
+	if err != nil {
+		// The exit status alone does not tell why, e.g. an unknown repository or failed authentication.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+			err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return "", redactCredentials(fmt.Errorf("failed to list remote refs of '%s': %w", r.repo, err), r.repo)
+	}
+
+	return string(out), nil
+}
+
+// findVersionCommit picks the commit of the highest semver tag from "git ls-remote --tags" output
+// that satisfies the constraints.
+func findVersionCommit(lsRemoteOutput string, constraints *semver.Constraints) (commit string, tag string, ok bool) {
+	var best *semver.Version
+
+	for _, line := range strings.Split(lsRemoteOutput, "\n") {
+		_, name, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+
+		name, isTag := strings.CutPrefix(name, "refs/tags/")
+		if !isTag || strings.HasSuffix(name, "^{}") {
+			continue
+		}
+
+		version, err := semver.NewVersion(name)
+		if err != nil || !constraints.Check(version) {
+			continue
+		}
+
+		if best == nil || version.GreaterThan(best) {
+			best = version
+			tag = name
+		}
+	}
+
+	if best == nil {
+		return "", "", false
+	}
+
+	commit, ok = findRefCommit(lsRemoteOutput, tag)
+	return commit, tag, ok
+}
+
+// findRefCommit picks the commit of the given ref from "git ls-remote" output,
+// preferring peeled tags over annotated tag objects.
+func findRefCommit(lsRemoteOutput, ref string) (string, bool) {
+	candidates := []string{
+		ref,
+		"refs/heads/" + ref,
+		"refs/tags/" + ref + "^{}",
+		"refs/tags/" + ref,
+	}
+
+	commitsByRef := make(map[string]string)
+	for _, line := range strings.Split(lsRemoteOutput, "\n") {
+		commit, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok {
+			commitsByRef[name] = commit
+		}
+	}
+
+	for _, candidate := range candidates {
+		if commit, ok := commitsByRef[candidate]; ok {
+			return commit, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,186 @@
+package installer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func TestDetectGitRemote(t *testing.T) {
+	tests := []struct {
+		name           string
+		sourceURL      string
+		expectGit      bool
+		expectedRepo   string
+		expectedSubdir string
+		expectedRef    string
+	}{
+		{
+			name:         "github shorthand",
+			sourceURL:    "github.com/github/awesome-copilot",
+			expectGit:    true,
+			expectedRepo: "https://github.com/github/awesome-copilot.git",
+		},
+		{
+			name:           "github shorthand with subdir and ref",
+			sourceURL:      "github.com/org/prompts//instructions?ref=v1.2.0",
+			expectGit:      true,
+			expectedRepo:   "https://github.com/org/prompts.git",
+			expectedSubdir: "instructions",
+			expectedRef:    "v1.2.0",
+		},
+		{
+			name:         "forced git getter",
+			sourceURL:    "git::https://example.com/prompts.git?ref=main",
+			expectGit:    true,
+			expectedRepo: "https://example.com/prompts.git",
+			expectedRef:  "main",
+		},
+		{
+			name:         "ssh shorthand",
+			sourceURL:    "git@github.com:org/prompts.git",
+			expectGit:    true,
+			expectedRepo: "ssh://git@github.com/org/prompts.git",
+		},
+		{
+			name:      "archive",
+			sourceURL: "https://example.com/prompts.zip",
+			expectGit: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, isGit, err := detectGitRemote(tt.sourceURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if isGit != tt.expectGit {
+				t.Fatalf("expected git %v, got %v", tt.expectGit, isGit)
+			}
+			if !isGit {
+				return
+			}
+
+			if remote.repo != tt.expectedRepo {
+				t.Errorf("expected repo %q, got %q", tt.expectedRepo, remote.repo)
+			}
+			if remote.subdir != tt.expectedSubdir {
+				t.Errorf("expected subdir %q, got %q", tt.expectedSubdir, remote.subdir)
+			}
+			if remote.ref() != tt.expectedRef {
+				t.Errorf("expected ref %q, got %q", tt.expectedRef, remote.ref())
+			}
+		})
+	}
+}
+
+func TestGitRemoteGetterURL(t *testing.T) {
+	remote, _, err := detectGitRemote("github.com/org/prompts//instructions?ref=main&depth=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	expected := "git::https://github.com/org/prompts.git//instructions?ref=" + commit
+	if got := remote.getterURL(commit); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if got := remote.cacheURL(); got != "https://github.com/org/prompts.git//instructions" {
+		t.Errorf("unexpected cache url %q", got)
+	}
+}
+
+func TestGitRemoteLsRemoteError(t *testing.T) {
+	remote := &gitRemote{repo: "file://user:secret@" + filepath.Join(t.TempDir(), "missing")}
+
+	_, err := remote.resolveCommit("main")
+	if err == nil {
+		t.Fatal("expected error for missing repository")
+	}
+	if !strings.Contains(err.Error(), "does not appear to be a git repository") {
+		t.Errorf("expected error to include the output of git, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected credentials to be redacted, got: %v", err)
+	}
+}
+
+func TestFindRefCommit(t *testing.T) {
+	output := "1111111111111111111111111111111111111111\tHEAD\n" +
+		"2222222222222222222222222222222222222222\trefs/heads/main\n" +
+		"3333333333333333333333333333333333333333\trefs/heads/feature/main\n" +
+		"4444444444444444444444444444444444444444\trefs/tags/v1.0.0\n" +
+		"5555555555555555555555555555555555555555\trefs/tags/v1.0.0^{}\n" +
+		"6666666666666666666666666666666666666666\trefs/tags/v1.1.0\n"
+
+	tests := []struct {
+		ref         string
+		expected    string
+		expectFound bool
+	}{
+		{"HEAD", "1111111111111111111111111111111111111111", true},
+		{"main", "2222222222222222222222222222222222222222", true},
+		{"v1.0.0", "5555555555555555555555555555555555555555", true},
+		{"v1.1.0", "6666666666666666666666666666666666666666", true},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			commit, found := findRefCommit(output, tt.ref)
+			if found != tt.expectFound {
+				t.Fatalf("expected found %v, got %v", tt.expectFound, found)
+			}
+			if commit != tt.expected {
+				t.Errorf("expected commit %q, got %q", tt.expected, commit)
+			}
+		})
+	}
+}
+
+func TestFindVersionCommit(t *testing.T) {
+	output := "1111111111111111111111111111111111111111\trefs/tags/v1.1.0\n" +
+		"2222222222222222222222222222222222222222\trefs/tags/v1.2.0\n" +
+		"3333333333333333333333333333333333333333\trefs/tags/v1.2.5\n" +
+		"4444444444444444444444444444444444444444\trefs/tags/v1.2.5^{}\n" +
+		"5555555555555555555555555555555555555555\trefs/tags/v1.3.0-rc.1\n" +
+		"6666666666666666666666666666666666666666\trefs/tags/v2.0.0\n" +
+		"7777777777777777777777777777777777777777\trefs/tags/nightly\n"
+
+	tests := []struct {
+		constraint     string
+		expectedTag    string
+		expectedCommit string
+		expectFound    bool
+	}{
+		{"^1.2", "v1.2.5", "4444444444444444444444444444444444444444", true},
+		{"~1.1", "v1.1.0", "1111111111111111111111111111111111111111", true},
+		{">= 1.0", "v2.0.0", "6666666666666666666666666666666666666666", true},
+		{"^3", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraints, err := semver.NewConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("invalid constraint: %v", err)
+			}
+
+			commit, tag, found := findVersionCommit(output, constraints)
+			if found != tt.expectFound {
+				t.Fatalf("expected found %v, got %v", tt.expectFound, found)
+			}
+			if tag != tt.expectedTag {
+				t.Errorf("expected tag %q, got %q", tt.expectedTag, tag)
+			}
+			if commit != tt.expectedCommit {
+				t.Errorf("expected commit %q, got %q", tt.expectedCommit, commit)
+			}
+		})
+	}
+}
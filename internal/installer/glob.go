@@ -0,0 +1,53 @@
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+)
+
+// globFiles returns the files that match pattern, relative to dir and sorted.
+// Besides the standard wildcards, pattern may contain "**" to match any number of directories. A pattern starting
+// with "../" matches files outside dir; their paths keep the leading "../".
+func globFiles(fs afero.Fs, dir, pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if !doublestar.ValidatePattern(pattern) {
+		return nil, fmt.Errorf("invalid pattern '%s'", pattern)
+	}
+
+	// Glob below the directory the pattern leaves dir to, since matching cannot go up.
+	prefix := ""
+	for pattern == ".." || strings.HasPrefix(pattern, "../") {
+		prefix += "../"
+		pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, ".."), "/")
+	}
+	if pattern == "" {
+		return nil, nil
+	}
+
+	// BasePathFs rejects every path below ".", which a relative dir leads to.
+	if base := filepath.Join(dir, filepath.FromSlash(prefix)); base != "." {
+		fs = afero.NewBasePathFs(fs, base)
+	}
+
+	matches, err := doublestar.Glob(afero.NewIOFS(fs), pattern, doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	for index, match := range matches {
+		matches[index] = filepath.FromSlash(prefix + match)
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+// matchPattern reports whether relPath matches pattern, using the same syntax as globFiles.
+func matchPattern(pattern, relPath string) (bool, error) {
+	return doublestar.Match(filepath.ToSlash(filepath.Clean(pattern)), filepath.ToSlash(relPath))
+}
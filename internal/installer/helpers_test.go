@@ -0,0 +1,45 @@
+package installer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hubblew/pim/internal/config"
+	"github.com/spf13/afero"
+)
+
+// newLocalInstallOptions creates a local source with the given files in a temporary directory
+// and returns options installing them with the flatten strategy to dir/out.
+func newLocalInstallOptions(t *testing.T, fs afero.Fs, files map[string]string) (*Options, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "source")
+
+	if err := fs.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write source file: %v", err)
+		}
+	}
+
+	options := &Options{
+		Config: &config.Config{
+			Version: 1,
+			Sources: []config.Source{{Name: "local", URL: sourceDir}},
+			Targets: []config.Target{{
+				Name:          "docs",
+				Output:        filepath.Join(dir, "out"),
+				StrategyType:  config.StrategyFlatten,
+				IncludeParsed: []config.Include{{Source: "local", File: "*.md"}},
+			}},
+		},
+		UserPrompter: NewAcceptAllPrompter(),
+		LockPath:     filepath.Join(dir, "pim.lock"),
+		Quiet:        true,
+	}
+
+	return options, dir
+}
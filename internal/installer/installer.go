@@ -1,136 +1,632 @@
 package installer
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-getter"
+	"github.com/hubblew/pim/internal/cache"
 	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/lockfile"
 	"github.com/hubblew/pim/internal/ui"
+	"github.com/hubblew/pim/internal/utils"
 	"github.com/spf13/afero"
 )
 
 type Installer struct {
-	fs afero.Fs
+	fs      afero.Fs
+	out     io.Writer
+	cache   *cache.Cache
+	offline bool
+	// locked is the lock state read at the start of the installation.
+	locked *lockfile.Lockfile
+	// resolved is the lock state produced by the installation.
+	resolved *lockfile.Lockfile
+	// localSources are the names of the sources that are local directories.
+	localSources map[string]bool
+	// mu guards resolved while sources are fetched concurrently.
+	mu sync.Mutex
 }
 
 type Options struct {
 	Config       *config.Config
 	UserPrompter UserPrompter
+	// LockPath is the path of the lockfile. Sources are not locked when it is empty.
+	LockPath string
+	// Update lists sources that are re-resolved to their latest revision, ignoring the lockfile.
+	Update []string
+	// Quiet suppresses progress output.
+	Quiet bool
+	// CacheDir is the directory of the persistent source cache.
+	// Fetched sources are discarded after the installation when it is empty.
+	CacheDir string
+	// Jobs is the maximum number of sources fetched concurrently. Sources are fetched one by one when it is below 1.
+	Jobs int
+	// Offline installs remote sources from the cache at their locked revision, without network access.
+	Offline bool
 }
 
 func NewInstaller(fs afero.Fs) *Installer {
 	return &Installer{
-		fs: fs,
+		fs:  fs,
+		out: os.Stdout,
 	}
 }
 
 func (i *Installer) Install(options *Options) error {
-	tempDir, err := os.MkdirTemp("", "pim-*")
+	cacheDir := options.CacheDir
+	if cacheDir == "" {
+		tempDir, err := os.MkdirTemp("", "pim-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func(path string) {
+			err := os.RemoveAll(path)
+			if err != nil {
+				fmt.Printf("failed to remove temp directory '%s': %v\n", path, err)
+			}
+		}(tempDir)
+		cacheDir = tempDir
+	}
+	// Fetched sources are always written to disk, even when installing to another filesystem.
+	i.cache = cache.New(afero.NewOsFs(), cacheDir)
+
+	if options.Quiet {
+		i.out = io.Discard
+	}
+	i.offline = options.Offline
+	if i.offline && options.LockPath == "" {
+		return fmt.Errorf("offline installation requires a lockfile")
+	}
+
+	if options.LockPath != "" {
+		var err error
+		if i.locked, err = lockfile.Load(i.fs, options.LockPath); err != nil {
+			return err
+		}
+		for _, name := range options.Update {
+			i.locked.Remove(name)
+		}
+		i.resolved = lockfile.New()
+	}
+
+	sourceDirsByName, err := i.fetchSources(options.Config.Sources, options.Jobs)
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return err
 	}
-	defer func(path string) {
-		err := os.RemoveAll(path)
-		if err != nil {
-			fmt.Printf("failed to remove temp directory '%s': %v\n", path, err)
+
+	for _, target := range options.Config.Targets {
+		if err := InstallTarget(i, &target, sourceDirsByName, options.UserPrompter); err != nil {
+			return err
 		}
-	}(tempDir)
+	}
 
-	sourceDirsByName := make(map[string]string, len(options.Config.Sources))
+	if i.resolved != nil {
+		if err := i.resolved.Save(i.fs, options.LockPath); err != nil {
+			return err
+		}
+	}
 
-	for _, source := range options.Config.Sources {
+	_, _ = fmt.Fprintln(i.out, "Installation complete!")
+	return nil
+}
+
+// ResolveSourceFile makes the source available on disk and returns the path of the given file of the source.
+// Remote sources are fetched at their locked revision, if any, into the cache of the options, which must be set.
+func ResolveSourceFile(fs afero.Fs, options *Options, source config.Source, file string) (string, error) {
+	if info, err := os.Stat(source.URL); err == nil && info.IsDir() {
+		return filepath.Join(source.URL, file), nil
+	}
+	if options.CacheDir == "" {
+		return "", fmt.Errorf("a cache directory is required to fetch source '%s'", source.Name)
+	}
+
+	i := NewInstaller(fs)
+	i.cache = cache.New(afero.NewOsFs(), options.CacheDir)
+	i.offline = options.Offline
+
+	if options.LockPath != "" {
+		var err error
+		if i.locked, err = lockfile.Load(fs, options.LockPath); err != nil {
+			return "", err
+		}
+		for _, name := range options.Update {
+			i.locked.Remove(name)
+		}
+	}
+
+	fetched, err := i.fetchSource(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch source '%s': %w", source.Name, redactCredentials(err, source.URL))
+	}
+	return filepath.Join(fetched.dir, file), nil
+}
+
+// fetchSources makes all sources available on disk, fetching remote sources concurrently with at most
+// jobs fetches running at the same time, and returns the directory of every source by name.
+// All failed sources are reported, in configuration order.
+func (i *Installer) fetchSources(sources []config.Source, jobs int) (map[string]string, error) {
+	sourceDirsByName := make(map[string]string, len(sources))
+	i.localSources = make(map[string]bool, len(sources))
+
+	var remoteSources []config.Source
+	for _, source := range sources {
 		if info, err := os.Stat(source.URL); err == nil && info.IsDir() {
+			if source.Ref != "" || source.Version != "" {
+				return nil, fmt.Errorf("source '%s' is a local directory, ref and version can only be used with git sources", source.Name)
+			}
 			sourceDirsByName[source.Name] = source.URL
+			i.localSources[source.Name] = true
 
 			continue
 		}
 
-		var sourceDir = filepath.Join(tempDir, source.Name)
+		remoteSources = append(remoteSources, source)
+	}
+
+	if len(remoteSources) == 0 {
+		return sourceDirsByName, nil
+	}
+
+	fetched := make([]*fetchedSource, len(remoteSources))
+	tasks := make([]ui.Task, len(remoteSources))
+	for index, source := range remoteSources {
+		label := fmt.Sprintf("Fetching source '%s' from %s...", source.Name, source.RawURL())
+		if i.offline {
+			label = fmt.Sprintf("Loading source '%s' from cache...", source.Name)
+		}
 
-		err = ui.RunWithSpinner(
-			fmt.Sprintf("Fetching source '%s' from %s...\n", source.Name, source.URL),
-			func() error {
-				client := &getter.Client{
-					Src:  source.URL,
-					Dst:  sourceDir,
-					Mode: getter.ClientModeDir,
+		tasks[index] = ui.Task{
+			Label: label,
+			Run: func() (string, error) {
+				result, err := i.fetchSource(source)
+				if err != nil {
+					return "", err
 				}
 
-				if err := client.Get(); err != nil {
-					return err
+				// Every task writes only its own slot, so no further synchronization is needed.
+				fetched[index] = result
+				if result.cached {
+					return fmt.Sprintf("Source '%s' loaded from cache at %s.", source.Name, result.revision), nil
 				}
+				return fmt.Sprintf("Source '%s' fetched successfully at %s.", source.Name, result.revision), nil
+			},
+		}
+	}
 
-				sourceDirsByName[source.Name] = sourceDir
-				return nil
+	var errs []error
+	for index, result := range ui.RunTasks(tasks, jobs, i.out) {
+		source := remoteSources[index]
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("failed to fetch source '%s': %w", source.Name, redactCredentials(result.Err, source.URL)))
+			continue
+		}
+		sourceDirsByName[source.Name] = fetched[index].dir
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return sourceDirsByName, nil
+}
+
+// fetchedSource is a remote source that is available on disk.
+type fetchedSource struct {
+	dir string
+	// revision is a human-readable description of the fetched revision.
+	revision string
+	// cached reports whether the source was reused from the cache instead of being downloaded.
+	cached bool
+}
+
+// fetchSource makes the source available in the cache and returns its location.
+// When locking is enabled, the source is fetched at its locked revision and the resolved revision is recorded.
+func (i *Installer) fetchSource(source config.Source) (*fetchedSource, error) {
+	locked := i.lockedSource(source)
+
+	remote, isGit, err := detectGitRemote(source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := lockfile.Source{
+		Name:    source.Name,
+		URL:     source.RawURL(),
+		Ref:     source.Ref,
+		Version: source.Version,
+		Files:   map[string]string{},
+	}
+
+	var entry *cache.Entry
+	cached := false
+
+	if isGit {
+		if locked != nil && locked.Commit != "" {
+			resolved.Tag, resolved.Commit = locked.Tag, locked.Commit
+		} else if i.offline {
+			return nil, errNotLockedOffline
+		} else if err := resolveGitSource(source, remote, &resolved); err != nil {
+			return nil, err
+		}
+
+		entry, cached = i.cache.Lookup(remote.cacheURL(), resolved.Commit)
+		if !cached && i.offline {
+			return nil, notCachedOfflineError(resolved.Commit)
+		}
+		if !cached {
+			entry, err = i.cache.Store(remote.cacheURL(), func(dir string) (string, error) {
+				return resolved.Commit, download(remote.getterURL(resolved.Commit), dir)
 			})
-		if err != nil {
-			return fmt.Errorf("failed to fetch source '%s': %w", source.Name, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if source.Ref != "" || source.Version != "" {
+			return nil, fmt.Errorf("ref and version can only be used with git sources")
+		}
+
+		if locked != nil && locked.Checksum != "" {
+			entry, cached = i.cache.Lookup(source.RawURL(), locked.Checksum)
+		} else if i.offline {
+			return nil, errNotLockedOffline
+		}
+		if !cached && i.offline {
+			return nil, notCachedOfflineError(locked.Checksum)
+		}
+		if !cached {
+			entry, err = i.cache.Store(source.RawURL(), func(dir string) (string, error) {
+				if err := download(source.URL, dir); err != nil {
+					return "", err
+				}
+				return utils.HashDir(i.cache.Fs(), dir)
+			})
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		fmt.Printf("Source '%s' fetched successfully.\n", source.Name)
+		resolved.Checksum = entry.Revision
+		if locked != nil && locked.Checksum != "" && locked.Checksum != resolved.Checksum {
+			return nil, fmt.Errorf(
+				"content does not match checksum %s recorded in the lockfile, run 'pim update %s' to accept the new content",
+				locked.Checksum, source.Name,
+			)
+		}
 	}
 
-	for _, target := range options.Config.Targets {
-		if err := InstallTarget(i, &target, sourceDirsByName, options.UserPrompter); err != nil {
-			return err
+	if i.resolved != nil {
+		i.mu.Lock()
+		i.resolved.Put(resolved)
+		i.mu.Unlock()
+	}
+
+	return &fetchedSource{
+		dir:      entry.SourceDir(),
+		revision: describeRevision(&resolved),
+		cached:   cached,
+	}, nil
+}
+
+var errNotLockedOffline = errors.New(
+	"source is not locked (or its configuration changed), run 'pim install' without --offline to lock and cache it",
+)
+
+func notCachedOfflineError(revision string) error {
+	return fmt.Errorf(
+		"locked revision %s is not in the cache, run 'pim install' without --offline to fetch it",
+		lockfile.ShortRevision(revision),
+	)
+}
+
+// redactCredentials removes the user name and password of the source URL, which may hold a token read from
+// the environment, from the message of err.
+func redactCredentials(err error, sourceURL string) error {
+	credentials := urlCredentials(sourceURL)
+	if credentials == "" || !strings.Contains(err.Error(), credentials) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), credentials, ""))
+}
+
+// download fetches the source at url into dir using go-getter.
+func download(url, dir string) error {
+	client := &getter.Client{
+		Src:  url,
+		Dst:  dir,
+		Mode: getter.ClientModeDir,
+	}
+
+	return client.Get()
+}
+
+// lockedSource returns the locked state of the source, or nil when the source is not locked
+// or its configuration changed since it was locked.
+func (i *Installer) lockedSource(source config.Source) *lockfile.Source {
+	if i.locked == nil {
+		return nil
+	}
+
+	locked := i.locked.Source(source.Name)
+	if locked == nil || locked.URL != source.RawURL() || locked.Ref != source.Ref || locked.Version != source.Version {
+		return nil
+	}
+
+	return locked
+}
+
+// resolveGitSource resolves the ref or version constraint of a git source to a commit.
+func resolveGitSource(source config.Source, remote *gitRemote, resolved *lockfile.Source) error {
+	if (source.Ref != "" || source.Version != "") && remote.ref() != "" {
+		return fmt.Errorf("ref and version cannot be combined with a 'ref' query parameter in the url")
+	}
+
+	var err error
+	if source.Version != "" {
+		resolved.Commit, resolved.Tag, err = remote.resolveVersion(source.Version)
+		return err
+	}
+
+	ref := source.Ref
+	if ref == "" {
+		ref = remote.ref()
+	}
+	resolved.Commit, err = remote.resolveCommit(ref)
+	return err
+}
+
+func describeRevision(source *lockfile.Source) string {
+	revision := lockfile.ShortRevision(source.Revision())
+
+	switch {
+	case source.Tag != "":
+		return fmt.Sprintf("%s (%s)", source.Tag, revision)
+	case source.Ref != "" && source.Ref != source.Commit:
+		return fmt.Sprintf("%s (%s)", source.Ref, revision)
+	default:
+		return revision
+	}
+}
+
+// lockFile records the content hash of a file included from a locked source and
+// verifies it against the hash recorded in the lockfile.
+func (i *Installer) lockFile(sourceName, srcPath, relPath string) error {
+	if i.resolved == nil {
+		return nil
+	}
+
+	resolved := i.resolved.Source(sourceName)
+	if resolved == nil {
+		// Local sources are not locked.
+		return nil
+	}
+
+	hash, err := utils.HashFile(i.fs, srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash file '%s': %w", srcPath, err)
+	}
+
+	key := filepath.ToSlash(relPath)
+	if locked := i.locked.Source(sourceName); locked != nil && locked.Revision() == resolved.Revision() {
+		if expected, ok := locked.Files[key]; ok && expected != hash {
+			return fmt.Errorf(
+				"file '@%s/%s' does not match hash %s recorded in the lockfile, run 'pim update %s' to accept the new content",
+				sourceName, key, expected, sourceName,
+			)
+		}
+	}
+
+	resolved.Files[key] = hash
+	return nil
+}
+
+// lockTarget records the content hash of every file written by the target.
+func (i *Installer) lockTarget(name string, written []string) error {
+	if i.resolved == nil {
+		return nil
+	}
+
+	files := make(map[string]string, len(written))
+	for _, path := range written {
+		if info, err := i.fs.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+
+		hash, err := utils.HashFile(i.fs, path)
+		if err != nil {
+			return fmt.Errorf("failed to hash output file '%s': %w", path, err)
 		}
+		files[filepath.ToSlash(path)] = hash
 	}
 
-	fmt.Println("Installation complete!")
+	i.resolved.PutTarget(lockfile.Target{Name: name, Files: files})
 	return nil
 }
 
+// ownedFiles returns the files written by the previous installation of the target, as recorded in the lockfile.
+func (i *Installer) ownedFiles(targetName string) []string {
+	if i.locked == nil {
+		return nil
+	}
+
+	locked := i.locked.Target(targetName)
+	if locked == nil {
+		return nil
+	}
+
+	owned := make([]string, 0, len(locked.Files))
+	for path := range locked.Files {
+		owned = append(owned, path)
+	}
+	sort.Strings(owned)
+	return owned
+}
+
 func InstallTarget(i *Installer, target *config.Target, sourceDirsByName map[string]string, prompter UserPrompter) error {
-	fmt.Printf("Installing target '%s' to %s...\n", target.Name, target.Output)
+	outputs := target.ResolvedOutputs()
+	_, _ = fmt.Fprintf(i.out, "Installing target '%s' to %s...\n", target.Name, strings.Join(outputPaths(outputs), ", "))
 
-	strategy, err := NewStrategy(i.fs, target.StrategyType, target.Output)
-	if err != nil {
-		return fmt.Errorf("failed to create strategy for target '%s': %w", target.Name, err)
+	outputFs := newRecordingFs(i.fs)
+
+	var strategyFs afero.Fs = outputFs
+	var renderer *renderingFs
+	if target.Render {
+		renderer = newRenderingFs(outputFs, target.VarsResolved)
+		strategyFs = renderer
+	}
+
+	// Rendered files differ from their source and cannot be linked.
+	link := target.Link
+	if target.Render {
+		link = config.LinkCopy
+	}
+
+	// The included files are resolved once and added to the strategy of every output.
+	strategies := make([]Strategy, 0, len(outputs))
+	closeStrategies := func() {
+		for _, strategy := range strategies {
+			if err := strategy.Close(); err != nil {
+				_, _ = fmt.Fprintf(i.out, "failed to close strategy for target '%s': %v\n", target.Name, err)
+			}
+		}
+	}
+
+	for _, output := range outputs {
+		strategy, err := NewStrategy(strategyFs, output.StrategyType, output.Output, output.Concat, link)
+		if err != nil {
+			closeStrategies()
+			return fmt.Errorf("failed to create strategy for target '%s': %w", target.Name, err)
+		}
+
+		if err := strategy.Initialize(prompter, i.ownedFiles(target.Name)); err != nil {
+			closeStrategies()
+			return err
+		}
+		strategies = append(strategies, strategy)
 	}
 
-	if err := strategy.Initialize(prompter); err != nil {
+	if err := addTargetFiles(i, target, sourceDirsByName, strategies, renderer); err != nil {
+		closeStrategies()
 		return err
 	}
-	defer func(strategy Strategy) {
+
+	for _, strategy := range strategies {
 		if err := strategy.Close(); err != nil {
-			fmt.Printf("failed to close strategy for target '%s': %v\n", target.Name, err)
+			return fmt.Errorf("failed to close strategy for target '%s': %w", target.Name, err)
 		}
-	}(strategy)
+	}
+
+	return i.lockTarget(target.Name, outputFs.written)
+}
 
+// outputPaths returns the paths of the outputs.
+func outputPaths(outputs []config.TargetOutput) []string {
+	paths := make([]string, len(outputs))
+	for i, output := range outputs {
+		paths[i] = output.Output
+	}
+	return paths
+}
+
+// addTargetFiles adds the included files of the target to every strategy. When renderer is set, the files
+// are rendered before they are added.
+func addTargetFiles(
+	i *Installer,
+	target *config.Target,
+	sourceDirsByName map[string]string,
+	strategies []Strategy,
+	renderer *renderingFs,
+) error {
 	for _, include := range target.IncludeParsed {
 		sourceDir, ok := sourceDirsByName[include.Source]
 		if !ok {
 			return fmt.Errorf("source '%s' not found", include.Source)
 		}
 
-		srcPath := filepath.Join(sourceDir, include.File)
-
-		// Use Glob to handle both literal paths and wildcard patterns
-		matches, err := afero.Glob(i.fs, srcPath)
+		// Glob handles both literal paths and wildcard patterns
+		relPaths, err := globFiles(i.fs, sourceDir, include.File)
 		if err != nil {
 			return fmt.Errorf("failed to expand pattern '%s': %w", include.File, err)
 		}
 
-		if len(matches) == 0 {
+		if len(relPaths) == 0 {
 			return fmt.Errorf("no files matched pattern '%s'", include.File)
 		}
 
-		for _, match := range matches {
-			// Get the relative path from sourceDir
-			relPath, err := filepath.Rel(sourceDir, match)
+		included := relPaths[:0]
+		for _, relPath := range relPaths {
+			excluded, err := isExcluded(target.ExcludeParsed, include.Source, relPath)
 			if err != nil {
-				return fmt.Errorf("failed to get relative path for '%s': %w", match, err)
+				return err
+			}
+			if !excluded {
+				included = append(included, relPath)
+			}
+		}
+
+		// A mistyped exclude must not silently empty the include.
+		if len(included) == 0 {
+			return fmt.Errorf("all files matched by pattern '%s' are excluded", include.File)
+		}
+
+		for _, relPath := range included {
+			match := filepath.Join(sourceDir, relPath)
+
+			if err := i.lockFile(include.Source, match, relPath); err != nil {
+				return err
+			}
+
+			if renderer != nil {
+				if err := renderer.render(match); err != nil {
+					return err
+				}
 			}
 
-			if err := strategy.AddFile(match, relPath); err != nil {
-				return fmt.Errorf("failed to add file '%s': %w", relPath, err)
+			for _, strategy := range strategies {
+				if err := strategy.AddFile(match, relPath, i.fileOrigin(include.Source)); err != nil {
+					return fmt.Errorf("failed to add file '%s': %w", relPath, err)
+				}
 			}
 
-			fmt.Printf("  ✓ %s\n", relPath)
+			_, _ = fmt.Fprintf(i.out, "  ✓ %s\n", relPath)
 		}
 	}
 
 	return nil
 }
+
+// fileOrigin returns the origin of the files of the given source, including its locked revision.
+func (i *Installer) fileOrigin(sourceName string) FileOrigin {
+	origin := FileOrigin{Source: sourceName, Local: i.localSources[sourceName]}
+	if i.resolved != nil {
+		if resolved := i.resolved.Source(sourceName); resolved != nil {
+			origin.Revision = resolved.Revision()
+		}
+	}
+	return origin
+}
+
+// isExcluded reports whether the file of the given source matches one of the exclude patterns.
+func isExcluded(excludes []config.Include, source, relPath string) (bool, error) {
+	for _, exclude := range excludes {
+		if exclude.Source != source {
+			continue
+		}
+
+		matched, err := matchPattern(exclude.File, relPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to match exclude pattern '%s': %w", exclude.File, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
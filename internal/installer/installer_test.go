@@ -0,0 +1,414 @@
+package installer
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hubblew/pim/internal/cache"
+	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/spf13/afero"
+)
+
+func TestInstallDoesNotPersistInterpolatedURL(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloads++
+		}
+		archive := zip.NewWriter(w)
+		file, _ := archive.Create("a.md")
+		_, _ = file.Write([]byte("A"))
+		_ = archive.Close()
+	}))
+	defer server.Close()
+
+	fs := afero.NewOsFs()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "pim.yaml")
+	rawURL := strings.Replace(server.URL, "http://", "http://${PIM_TEST_TOKEN}@", 1) + "/prompts.zip"
+	content := "version: 1\nsources:\n  - name: remote\n    url: " + rawURL + "\n" +
+		"targets:\n  - name: docs\n    output: " + filepath.Join(dir, "out") + "\n    include:\n      - \"@remote/*.md\"\n"
+	if err := afero.WriteFile(fs, configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	install := func(token string) {
+		t.Setenv("PIM_TEST_TOKEN", token)
+		cfg, err := config.LoadConfig(fs, configPath, dir)
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		options := &Options{
+			Config:       cfg,
+			UserPrompter: NewAcceptAllPrompter(),
+			LockPath:     filepath.Join(dir, "pim.lock"),
+			CacheDir:     filepath.Join(dir, "cache"),
+			Quiet:        true,
+		}
+		if err := NewInstaller(fs).Install(options); err != nil {
+			t.Fatalf("Install() error = %v", err)
+		}
+	}
+
+	install("s3cr3t")
+	// Another token resolves to the same locked source and reuses the cached download.
+	install("0th3r")
+	if downloads != 1 {
+		t.Errorf("expected the locked source to be downloaded once, got %d downloads", downloads)
+	}
+
+	lock, err := afero.ReadFile(fs, filepath.Join(dir, "pim.lock"))
+	if err != nil {
+		t.Fatalf("failed to read lockfile: %v", err)
+	}
+	if strings.Contains(string(lock), "s3cr3t") || !strings.Contains(string(lock), rawURL) {
+		t.Errorf("expected lockfile to contain the url as written, got:\n%s", lock)
+	}
+
+	entries, err := cache.New(fs, filepath.Join(dir, "cache")).List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one cache entry, got %v, %v", entries, err)
+	}
+	metadata, err := afero.ReadFile(fs, filepath.Join(dir, "cache", entries[0].Key, "entry.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read cache metadata: %v", err)
+	}
+	if strings.Contains(string(metadata), "s3cr3t") {
+		t.Errorf("expected cache metadata without the token, got:\n%s", metadata)
+	}
+}
+
+func TestInstallOffline(t *testing.T) {
+	const sourceURL = "https://example.com/prompts.zip"
+
+	fs := afero.NewOsFs()
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	lockPath := filepath.Join(dir, "pim.lock")
+
+	entry, err := cache.New(fs, cacheDir).Store(sourceURL, func(dir string) (string, error) {
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+		if err := afero.WriteFile(fs, filepath.Join(dir, "a.md"), []byte("A"), 0644); err != nil {
+			return "", err
+		}
+		return "sha256:cached", nil
+	})
+	if err != nil {
+		t.Fatalf("failed to populate cache: %v", err)
+	}
+
+	newOptions := func() *Options {
+		return &Options{
+			Config: &config.Config{
+				Version: 1,
+				Sources: []config.Source{{Name: "remote", URL: sourceURL}},
+				Targets: []config.Target{{
+					Name:          "docs",
+					Output:        filepath.Join(dir, "out"),
+					StrategyType:  config.StrategyFlatten,
+					IncludeParsed: []config.Include{{Source: "remote", File: "*.md"}},
+					// Files of remote sources are copied from the cache.
+					Link: config.LinkSymlink,
+				}},
+			},
+			UserPrompter: NewAcceptAllPrompter(),
+			LockPath:     lockPath,
+			CacheDir:     cacheDir,
+			Offline:      true,
+			Quiet:        true,
+		}
+	}
+
+	err = NewInstaller(fs).Install(newOptions())
+	if err == nil || !strings.Contains(err.Error(), "source 'remote'") || !strings.Contains(err.Error(), "not locked") {
+		t.Fatalf("expected not locked error for source, got %v", err)
+	}
+
+	lock := lockfile.New()
+	lock.Put(lockfile.Source{Name: "remote", URL: sourceURL, Checksum: entry.Revision})
+	if err := lock.Save(fs, lockPath); err != nil {
+		t.Fatalf("failed to save lockfile: %v", err)
+	}
+
+	if err := NewInstaller(fs).Install(newOptions()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	content, err := afero.ReadFile(fs, filepath.Join(dir, "out", "a.md"))
+	if err != nil || string(content) != "A" {
+		t.Errorf("expected file installed from cache, got %q, %v", content, err)
+	}
+	if info, err := os.Lstat(filepath.Join(dir, "out", "a.md")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("expected file of remote source to be copied, got %v, %v", info, err)
+	}
+
+	if err := cache.New(fs, cacheDir).Clean(); err != nil {
+		t.Fatalf("failed to clean cache: %v", err)
+	}
+	err = NewInstaller(fs).Install(newOptions())
+	if err == nil || !strings.Contains(err.Error(), "not in the cache") {
+		t.Fatalf("expected not cached error, got %v", err)
+	}
+}
+
+func TestInstallReportsAllFailedSources(t *testing.T) {
+	dir := t.TempDir()
+
+	options := &Options{
+		Config: &config.Config{
+			Version: 1,
+			Sources: []config.Source{
+				{Name: "first", URL: "https://example.com/first.zip"},
+				{Name: "local", URL: dir},
+				{Name: "second", URL: "https://example.com/second.zip"},
+			},
+		},
+		UserPrompter: NewAcceptAllPrompter(),
+		LockPath:     filepath.Join(dir, "pim.lock"),
+		CacheDir:     filepath.Join(dir, "cache"),
+		Offline:      true,
+		Jobs:         2,
+		Quiet:        true,
+	}
+
+	err := NewInstaller(afero.NewOsFs()).Install(options)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	message := err.Error()
+	first, second := strings.Index(message, "source 'first'"), strings.Index(message, "source 'second'")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("expected both failures in configuration order, got:\n%s", message)
+	}
+	if strings.Contains(message, "source 'local'") {
+		t.Errorf("expected local source not to fail, got:\n%s", message)
+	}
+}
+
+func TestInstallExclude(t *testing.T) {
+	fs := afero.NewOsFs()
+	options, dir := newLocalInstallOptions(t, fs, map[string]string{
+		"guide.md":       "guide",
+		"draft-guide.md": "draft",
+		"README.md":      "readme",
+	})
+	options.Config.Targets[0].ExcludeParsed = []config.Include{
+		{Source: "local", File: "draft-*.md"},
+		{Source: "local", File: "README.md"},
+		{Source: "other", File: "guide.md"},
+	}
+
+	if err := NewInstaller(fs).Install(options); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	infos, err := afero.ReadDir(fs, filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name() != "guide.md" {
+		t.Errorf("expected only guide.md to be installed, got %v", infos)
+	}
+}
+
+func TestInstallExcludeEverything(t *testing.T) {
+	fs := afero.NewOsFs()
+	options, _ := newLocalInstallOptions(t, fs, map[string]string{"guide.md": "guide"})
+	options.Config.Targets[0].ExcludeParsed = []config.Include{{Source: "local", File: "**"}}
+
+	err := NewInstaller(fs).Install(options)
+	if err == nil || !strings.Contains(err.Error(), "all files matched by pattern '*.md' are excluded") {
+		t.Fatalf("expected error for include emptied by exclude, got %v", err)
+	}
+}
+
+func TestInstallOutputs(t *testing.T) {
+	fs := afero.NewOsFs()
+	options, dir := newLocalInstallOptions(t, fs, map[string]string{"a.md": "A\n", "b.md": "B\n"})
+	target := &options.Config.Targets[0]
+	target.Outputs = []config.TargetOutput{
+		{Output: filepath.Join(dir, "AGENTS.md"), StrategyType: config.StrategyConcat},
+		{Output: filepath.Join(dir, "out"), StrategyType: config.StrategyFlatten},
+	}
+
+	// The second installation must recognize the files of every output as owned.
+	for range 2 {
+		if err := NewInstaller(fs).Install(options); err != nil {
+			t.Fatalf("Install() error = %v", err)
+		}
+	}
+
+	content, err := afero.ReadFile(fs, filepath.Join(dir, "AGENTS.md"))
+	if err != nil {
+		t.Fatalf("failed to read concat output: %v", err)
+	}
+	if !strings.Contains(string(content), "A\n") || !strings.Contains(string(content), "B\n") {
+		t.Errorf("expected concat output to contain both files, got %q", content)
+	}
+	for _, name := range []string{"a.md", "b.md"} {
+		if _, err := fs.Stat(filepath.Join(dir, "out", name)); err != nil {
+			t.Errorf("expected %s to be installed to the flatten output: %v", name, err)
+		}
+	}
+
+	lock, err := lockfile.Load(fs, options.LockPath)
+	if err != nil {
+		t.Fatalf("failed to load lockfile: %v", err)
+	}
+	if locked := lock.Target("docs"); locked == nil || len(locked.Files) != 3 {
+		t.Errorf("expected the files of both outputs to be locked, got %+v", locked)
+	}
+}
+
+func TestInstallLink(t *testing.T) {
+	tests := []struct {
+		name         string
+		link         config.LinkMode
+		render       bool
+		expectedMode os.FileMode
+		expectedLink string
+		sameFile     bool
+	}{
+		{name: "copy", link: config.LinkCopy},
+		{name: "symlink", link: config.LinkSymlink, expectedMode: os.ModeSymlink, expectedLink: filepath.Join("..", "source", "a.md")},
+		{name: "hardlink", link: config.LinkHardlink, sameFile: true},
+		{name: "rendered files are copied", link: config.LinkSymlink, render: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewOsFs()
+			options, dir := newLocalInstallOptions(t, fs, map[string]string{"a.md": "A\n", "b.md": "B\n"})
+			target := &options.Config.Targets[0]
+			target.Link = tt.link
+			target.Render = tt.render
+
+			if err := NewInstaller(fs).Install(options); err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			if drifts, err := Check(fs, options); err != nil || len(drifts) > 0 {
+				t.Errorf("expected no drift after installation, got %v, %v", drifts, err)
+			}
+
+			srcPath := filepath.Join(dir, "source", "a.md")
+			dstPath := filepath.Join(dir, "out", "a.md")
+			info, err := os.Lstat(dstPath)
+			if err != nil {
+				t.Fatalf("failed to stat installed file: %v", err)
+			}
+			if info.Mode().Type() != tt.expectedMode {
+				t.Errorf("expected file type %v, got %v", tt.expectedMode, info.Mode().Type())
+			}
+			if tt.expectedLink != "" {
+				if link, err := os.Readlink(dstPath); err != nil || link != tt.expectedLink {
+					t.Errorf("expected link to %q, got %q, %v", tt.expectedLink, link, err)
+				}
+			}
+			srcInfo, _ := os.Stat(srcPath)
+			if os.SameFile(info, srcInfo) != tt.sameFile {
+				t.Errorf("expected installed file to be the source file: %v", tt.sameFile)
+			}
+
+			// Reinstalling replaces the owned links and removes the files whose source is gone,
+			// without touching the sources.
+			if err := fs.Remove(filepath.Join(dir, "source", "b.md")); err != nil {
+				t.Fatalf("failed to remove source file: %v", err)
+			}
+			options.UserPrompter = &mockPrompter{allowOverwrite: false}
+			if err := NewInstaller(fs).Install(options); err != nil {
+				t.Fatalf("second Install() error = %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "out", "b.md")); !os.IsNotExist(err) {
+				t.Errorf("expected b.md to be removed, got %v", err)
+			}
+			if content, err := afero.ReadFile(fs, srcPath); err != nil || string(content) != "A\n" {
+				t.Errorf("expected source file to be unchanged, got %q, %v", content, err)
+			}
+			if content, err := afero.ReadFile(fs, dstPath); err != nil || string(content) != "A\n" {
+				t.Errorf("expected installed file to have the source content, got %q, %v", content, err)
+			}
+		})
+	}
+}
+
+func TestInstallRender(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategyType config.StrategyType
+		output       string
+		files        map[string]string
+		expectError  string
+		expected     map[string]string
+	}{
+		{
+			name:         "flatten",
+			strategyType: config.StrategyFlatten,
+			output:       "out",
+			files:        map[string]string{"guide.md": "# {{ .ProjectName }}\nWritten in {{ .Language }}.\n"},
+			expected:     map[string]string{"out/guide.md": "# pim\nWritten in Go.\n"},
+		},
+		{
+			name:         "concat",
+			strategyType: config.StrategyConcat,
+			output:       "AGENTS.md",
+			files:        map[string]string{"guide.md": "---\ndescription: {{ .ProjectName }}\n---\nUse {{ .Language }}.\n"},
+			expected: map[string]string{
+				"AGENTS.md": "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\nUse Go.\n\n",
+			},
+		},
+		{
+			name:         "undefined variable",
+			strategyType: config.StrategyFlatten,
+			output:       "out",
+			files:        map[string]string{"guide.md": "{{ .Missing }}\n"},
+			expectError:  "failed to render file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewOsFs()
+			options, dir := newLocalInstallOptions(t, fs, tt.files)
+			target := &options.Config.Targets[0]
+			target.Output = filepath.Join(dir, tt.output)
+			target.StrategyType = tt.strategyType
+			target.Render = true
+			target.VarsResolved = map[string]any{"ProjectName": "pim", "Language": "Go"}
+
+			err := NewInstaller(fs).Install(options)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			for path, expected := range tt.expected {
+				content, err := afero.ReadFile(fs, filepath.Join(dir, path))
+				if err != nil {
+					t.Fatalf("failed to read output: %v", err)
+				}
+				if string(content) != expected {
+					t.Errorf("expected %s to be %q, got %q", path, expected, content)
+				}
+			}
+
+			source, _ := afero.ReadFile(fs, filepath.Join(dir, "source", "guide.md"))
+			if string(source) != tt.files["guide.md"] {
+				t.Errorf("expected source file to be unchanged, got %q", source)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package installer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+// instructionFrontmatter holds the frontmatter keys of instruction files that agent strategies translate
+// into the format of each agent.
+type instructionFrontmatter struct {
+	Description string `yaml:"description"`
+	// ApplyTo is a comma-separated string or a list of glob patterns of the files the instructions apply to.
+	ApplyTo any `yaml:"applyTo"`
+}
+
+// readInstruction returns the frontmatter and the body without frontmatter of an instruction file.
+func readInstruction(fs afero.Fs, srcPath string) (instructionFrontmatter, []byte, error) {
+	var frontmatter instructionFrontmatter
+	if err := utils.ReadFrontmatter(fs, srcPath, &frontmatter); err != nil {
+		return frontmatter, nil, fmt.Errorf("failed to read frontmatter of '%s': %w", srcPath, err)
+	}
+
+	content, err := afero.ReadFile(fs, srcPath)
+	if err != nil {
+		return frontmatter, nil, fmt.Errorf("failed to read source file '%s': %w", srcPath, err)
+	}
+
+	body := utils.StripFrontmatter(content)
+	if len(body) != len(content) {
+		body = []byte(strings.TrimLeft(string(body), "\r\n"))
+	}
+	return frontmatter, body, nil
+}
+
+// globs returns the normalized patterns of applyTo: trimmed, relative and with forward slashes.
+// Empty patterns are dropped and invalid patterns are rejected.
+func (f instructionFrontmatter) globs() ([]string, error) {
+	var patterns []string
+	switch applyTo := f.ApplyTo.(type) {
+	case nil:
+	case string:
+		patterns = strings.Split(applyTo, ",")
+	case []any:
+		for _, pattern := range applyTo {
+			value, ok := pattern.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid applyTo pattern: %v (must be a string)", pattern)
+			}
+			patterns = append(patterns, value)
+		}
+	default:
+		return nil, fmt.Errorf("invalid applyTo: %v (must be a string or a list of strings)", applyTo)
+	}
+
+	var globs []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(pattern), `\`, "/"), "./")
+		if pattern == "" {
+			continue
+		}
+		if path.IsAbs(pattern) || !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid applyTo pattern: %s", pattern)
+		}
+		globs = append(globs, pattern)
+	}
+	return globs, nil
+}
+
+// appliesToAllFiles returns whether the globs match every file, which is the case without globs.
+func appliesToAllFiles(globs []string) bool {
+	for _, glob := range globs {
+		if glob == "**" || glob == "**/*" {
+			return true
+		}
+	}
+	return len(globs) == 0
+}
+
+// instructionName returns the base name of an instruction file without its extensions,
+// e.g. "go" for "instructions/go.instructions.md".
+func instructionName(relativePath string) string {
+	name := path.Base(strings.ReplaceAll(relativePath, `\`, "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return strings.TrimSuffix(name, ".instructions")
+}
@@ -0,0 +1,171 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+// managedOutput tracks which files of an output directory are owned by PIM.
+//
+// Only files written by the previous installation are removed when the output is initialized.
+// Any other existing file is left untouched and only overwritten after the user confirms it.
+type managedOutput struct {
+	fs         afero.Fs
+	outputPath string
+	prompter   UserPrompter
+	// written maps the files written by this installation to the included file each was written from.
+	written map[string]string
+	// overwriteGenerated allows overwriting files with the PIM generation marker without confirmation.
+	overwriteGenerated bool
+	// link is the link mode of the files installed with installFile.
+	link config.LinkMode
+}
+
+func newManagedOutput(fs afero.Fs, outputPath string) *managedOutput {
+	return &managedOutput{
+		fs:         fs,
+		outputPath: outputPath,
+		written:    make(map[string]string),
+	}
+}
+
+// initialize removes the owned files inside the output directory and creates the directory.
+func (m *managedOutput) initialize(prompter UserPrompter, owned []string) error {
+	m.prompter = prompter
+
+	for _, path := range owned {
+		path = filepath.Clean(filepath.FromSlash(path))
+		if !isWithinOutput(path, m.outputPath) || path == filepath.Clean(m.outputPath) {
+			continue
+		}
+
+		if err := m.fs.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file '%s': %w", path, err)
+		}
+		m.removeEmptyParents(path)
+	}
+
+	if err := m.fs.MkdirAll(m.outputPath, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", m.outputPath, err)
+	}
+	return nil
+}
+
+// removeEmptyParents removes the directories between path and the output directory that became empty.
+func (m *managedOutput) removeEmptyParents(path string) {
+	root := filepath.Clean(m.outputPath)
+	for dir := filepath.Dir(path); dir != root && isWithinOutput(dir, root); dir = filepath.Dir(dir) {
+		entries, err := afero.ReadDir(m.fs, dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := m.fs.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// installFile installs srcPath, the file at relativePath in the source of origin, at dstPath and asks for confirmation
+// before overwriting a file PIM does not own. Files of local sources are linked according to the link mode, while
+// files of remote sources and files on filesystems that cannot create links are copied.
+func (m *managedOutput) installFile(srcPath, relativePath, dstPath string, origin FileOrigin) error {
+	dstPath = filepath.Clean(dstPath)
+	if err := m.claim(dstPath, origin.Path(relativePath)); err != nil {
+		return err
+	}
+
+	// The existing file is replaced instead of overwritten, so that nothing is written through a link to a source.
+	if err := m.fs.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file '%s': %w", dstPath, err)
+	}
+
+	if origin.Local {
+		err := m.linkFile(srcPath, dstPath)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, utils.ErrLinkNotSupported) {
+			return fmt.Errorf("failed to link file '%s': %w", dstPath, err)
+		}
+	}
+	return utils.CopyFile(m.fs, srcPath, dstPath)
+}
+
+// linkFile links dstPath to srcPath according to the link mode. It returns utils.ErrLinkNotSupported when the
+// file must be copied instead.
+func (m *managedOutput) linkFile(srcPath, dstPath string) error {
+	switch m.link {
+	case config.LinkSymlink:
+		return utils.SymlinkFile(m.fs, srcPath, dstPath)
+	case config.LinkHardlink:
+		return utils.HardLinkFile(m.fs, srcPath, dstPath)
+	default:
+		return utils.ErrLinkNotSupported
+	}
+}
+
+// writeFile writes content converted from the included file from to dstPath, asking for confirmation before
+// overwriting a file PIM does not own.
+func (m *managedOutput) writeFile(dstPath, from string, content []byte) error {
+	dstPath = filepath.Clean(dstPath)
+	if err := m.claim(dstPath, from); err != nil {
+		return err
+	}
+
+	if err := m.fs.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(dstPath), err)
+	}
+	if err := afero.WriteFile(m.fs, dstPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", dstPath, err)
+	}
+	return nil
+}
+
+// claim records that dstPath is written from the included file from by this installation. An existing file that
+// was not written by it may only be overwritten after the user confirms it, and two included files cannot be
+// written to the same path.
+func (m *managedOutput) claim(dstPath, from string) error {
+	if claimedBy, ok := m.written[dstPath]; ok {
+		if claimedBy != from {
+			return fmt.Errorf("'%s' and '%s' are both installed to '%s'", claimedBy, from, dstPath)
+		}
+		return nil
+	}
+
+	if m.exists(dstPath) && !m.isGenerated(dstPath) {
+		allowOverride, err := m.prompter.ConfirmOverwrite(dstPath)
+		if err != nil {
+			return fmt.Errorf("failed to prompt for file overwrite: %w", err)
+		}
+		if !allowOverride {
+			return fmt.Errorf("user declined to override file '%s'", dstPath)
+		}
+	}
+
+	m.written[dstPath] = from
+	return nil
+}
+
+// exists reports whether path exists, including links whose target is missing.
+func (m *managedOutput) exists(path string) bool {
+	if lstater, ok := m.fs.(afero.Lstater); ok {
+		_, _, err := lstater.LstatIfPossible(path)
+		return err == nil
+	}
+	_, err := m.fs.Stat(path)
+	return err == nil
+}
+
+func (m *managedOutput) isGenerated(path string) bool {
+	if !m.overwriteGenerated {
+		return false
+	}
+	generated, err := IsPimGenerated(m.fs, path)
+	return err == nil && generated
+}
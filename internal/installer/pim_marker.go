@@ -3,23 +3,22 @@ package installer
 import (
 	"fmt"
 	"io"
+	"slices"
 
+	"github.com/goccy/go-yaml"
 	"github.com/hubblew/pim/internal/utils"
 	"github.com/spf13/afero"
 )
 
-const generatedByPim = "github.com/hubblew/pim-cli"
+const (
+	generatedByKey = "generatedBy"
+	generatedByPim = "github.com/hubblew/pim-cli"
+)
 
 type frontmatterHeader struct {
 	GeneratedBy string `yaml:"generatedBy"`
 }
 
-func defaultHeader() frontmatterHeader {
-	return frontmatterHeader{
-		GeneratedBy: generatedByPim,
-	}
-}
-
 // IsPimGenerated checks if the markdown file at the given path
 // contains a frontmatter block with the "generatedBy" key set to "github.com/hubblew/pim-cli".
 func IsPimGenerated(fs afero.Fs, path string) (bool, error) {
@@ -37,7 +36,12 @@ func IsPimGenerated(fs afero.Fs, path string) (bool, error) {
 
 // AddGeneratedByPimHeader writes the PIM generation marker to the given file's frontmatter.
 func AddGeneratedByPimHeader(file io.Writer) error {
-	header := defaultHeader()
+	return addPimHeader(file, nil)
+}
+
+// addPimHeader writes a frontmatter with the PIM generation marker followed by the given fields.
+func addPimHeader(file io.Writer, fields yaml.MapSlice) error {
+	header := slices.Concat(yaml.MapSlice{{Key: generatedByKey, Value: generatedByPim}}, fields)
 
 	if err := utils.WriteFrontmatter(file, header); err != nil {
 		return fmt.Errorf("failed to write frontmatter: %w", err)
@@ -0,0 +1,124 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+// Plan describes the changes an installation would make to the filesystem, without applying them.
+type Plan struct {
+	Targets []TargetPlan
+
+	base    afero.Fs
+	overlay *utils.OverlayFs
+}
+
+// TargetPlan lists the changes an installation would make to the outputs of a single target.
+type TargetPlan struct {
+	Name    string
+	Outputs []string
+	Changes []utils.Change
+}
+
+// PlanInstall runs the installation against an in-memory overlay of fs and returns the
+// resulting changes grouped by target. Nothing is written to fs.
+func PlanInstall(fs afero.Fs, options *Options) (*Plan, error) {
+	overlay := utils.NewOverlayFs(fs)
+	if err := NewInstaller(overlay).Install(options); err != nil {
+		return nil, err
+	}
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare installed files: %w", err)
+	}
+
+	plan := &Plan{base: fs, overlay: overlay}
+	for _, target := range options.Config.Targets {
+		targetPlan := TargetPlan{Name: target.Name, Outputs: outputPaths(target.ResolvedOutputs())}
+		for _, change := range changes {
+			if slices.ContainsFunc(targetPlan.Outputs, func(output string) bool {
+				return isWithinOutput(change.Path, output)
+			}) {
+				targetPlan.Changes = append(targetPlan.Changes, change)
+			}
+		}
+		plan.Targets = append(plan.Targets, targetPlan)
+	}
+
+	return plan, nil
+}
+
+// isWithinOutput reports whether path is the output file or lies inside the output directory.
+func isWithinOutput(path, output string) bool {
+	output = filepath.Clean(output)
+	return path == output || strings.HasPrefix(path, output+string(filepath.Separator))
+}
+
+// HasChanges reports whether the installation would change any target output.
+func (p *Plan) HasChanges() bool {
+	for _, target := range p.Targets {
+		if len(target.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDiff writes a unified diff between the current content of the changed file and
+// the content the installation would produce.
+func (p *Plan) WriteDiff(w io.Writer, change utils.Change) error {
+	diff := difflib.UnifiedDiff{
+		FromFile: "a/" + filepath.ToSlash(change.Path),
+		ToFile:   "b/" + filepath.ToSlash(change.Path),
+		Context:  3,
+	}
+
+	if change.Kind != utils.ChangeCreated {
+		content, err := afero.ReadFile(p.base, change.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read file '%s': %w", change.Path, err)
+		}
+		diff.A = splitLines(string(content))
+	} else {
+		diff.FromFile = "/dev/null"
+	}
+
+	if change.Kind != utils.ChangeDeleted {
+		content, err := afero.ReadFile(p.overlay, change.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read file '%s': %w", change.Path, err)
+		}
+		diff.B = splitLines(string(content))
+	} else {
+		diff.ToFile = "/dev/null"
+	}
+
+	if err := difflib.WriteUnifiedDiff(w, diff); err != nil {
+		return fmt.Errorf("failed to write diff for '%s': %w", change.Path, err)
+	}
+	return nil
+}
+
+// splitLines splits content into lines that keep their line endings.
+// Unlike difflib.SplitLines, it does not report an empty line after a trailing newline.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,78 @@
+package installer
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+func TestPlanInstall(t *testing.T) {
+	fs := afero.NewOsFs()
+	options, dir := newLocalInstallOptions(t, fs, map[string]string{"a.md": "A\n", "b.md": "B\n"})
+	outputDir := filepath.Join(dir, "out")
+
+	if err := NewInstaller(fs).Install(options); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(dir, "source", "a.md"), []byte("A2\n"), 0644); err != nil {
+		t.Fatalf("failed to update source file: %v", err)
+	}
+	if err := fs.Remove(filepath.Join(dir, "source", "b.md")); err != nil {
+		t.Fatalf("failed to remove source file: %v", err)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(dir, "source", "c.md"), []byte("C\n"), 0644); err != nil {
+		t.Fatalf("failed to write source file: %v", err)
+	}
+
+	plan, err := PlanInstall(fs, options)
+	if err != nil {
+		t.Fatalf("PlanInstall() error = %v", err)
+	}
+
+	expected := []TargetPlan{{
+		Name:    "docs",
+		Outputs: []string{outputDir},
+		Changes: []utils.Change{
+			{Path: filepath.Join(outputDir, "a.md"), Kind: utils.ChangeUpdated},
+			{Path: filepath.Join(outputDir, "b.md"), Kind: utils.ChangeDeleted},
+			{Path: filepath.Join(outputDir, "c.md"), Kind: utils.ChangeCreated},
+		},
+	}}
+	if !reflect.DeepEqual(plan.Targets, expected) {
+		t.Fatalf("expected plan %v, got %v", expected, plan.Targets)
+	}
+	if !plan.HasChanges() {
+		t.Error("expected plan to have changes")
+	}
+
+	content, err := afero.ReadFile(fs, filepath.Join(outputDir, "a.md"))
+	if err != nil || string(content) != "A\n" {
+		t.Errorf("expected output to be untouched, got %q, %v", content, err)
+	}
+
+	var buf bytes.Buffer
+	for _, change := range plan.Targets[0].Changes {
+		if err := plan.WriteDiff(&buf, change); err != nil {
+			t.Fatalf("WriteDiff() error = %v", err)
+		}
+	}
+
+	diff := buf.String()
+	for _, fragment := range []string{
+		"--- a/" + filepath.ToSlash(filepath.Join(outputDir, "a.md")),
+		"-A\n+A2\n",
+		"+++ /dev/null",
+		"-B\n",
+		"--- /dev/null",
+		"+C\n",
+	} {
+		if !strings.Contains(diff, fragment) {
+			t.Errorf("diff missing expected fragment %q:\n%s", fragment, diff)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+// recordingFs records the files opened for writing and the links created, so that the outputs of a strategy can be
+// tracked.
+type recordingFs struct {
+	afero.Fs
+	written []string
+	seen    map[string]bool
+}
+
+var (
+	_ afero.Linker     = (*recordingFs)(nil)
+	_ afero.Lstater    = (*recordingFs)(nil)
+	_ utils.HardLinker = (*recordingFs)(nil)
+)
+
+func newRecordingFs(fs afero.Fs) *recordingFs {
+	return &recordingFs{
+		Fs:   fs,
+		seen: make(map[string]bool),
+	}
+}
+
+func (r *recordingFs) Create(name string) (afero.File, error) {
+	r.record(name)
+	return r.Fs.Create(name)
+}
+
+func (r *recordingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if utils.HasWriteFlag(flag) {
+		r.record(name)
+	}
+	return r.Fs.OpenFile(name, flag, perm)
+}
+
+func (r *recordingFs) record(name string) {
+	name = filepath.Clean(name)
+	if !r.seen[name] {
+		r.seen[name] = true
+		r.written = append(r.written, name)
+	}
+}
+
+// SymlinkIfPossible creates a symbolic link with the underlying filesystem and records it as written.
+func (r *recordingFs) SymlinkIfPossible(oldname, newname string) error {
+	linker, ok := r.Fs.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	if err := linker.SymlinkIfPossible(oldname, newname); err != nil {
+		return err
+	}
+	r.record(newname)
+	return nil
+}
+
+// LinkIfPossible creates a hard link with the underlying filesystem and records it as written.
+func (r *recordingFs) LinkIfPossible(oldname, newname string) error {
+	if err := utils.HardLinkFile(r.Fs, oldname, newname); err != nil {
+		return err
+	}
+	r.record(newname)
+	return nil
+}
+
+// LstatIfPossible returns the file info of name without following a final symbolic link, when the underlying
+// filesystem supports it.
+func (r *recordingFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if lstater, ok := r.Fs.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	info, err := r.Fs.Stat(name)
+	return info, false, err
+}
@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hubblew/pim/internal/templates"
+	"github.com/hubblew/pim/internal/utils"
+	"github.com/spf13/afero"
+)
+
+// renderingFs serves the rendered content of the source files added with render instead of their content on disk,
+// so that strategies read rendered files without writing them anywhere.
+type renderingFs struct {
+	afero.Fs
+	vars     map[string]any
+	rendered afero.Fs
+}
+
+func newRenderingFs(fs afero.Fs, vars map[string]any) *renderingFs {
+	return &renderingFs{
+		Fs:       fs,
+		vars:     vars,
+		rendered: afero.NewMemMapFs(),
+	}
+}
+
+// render runs the source file through text/template with the variables of the target.
+func (r *renderingFs) render(path string) error {
+	content, err := afero.ReadFile(r.Fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+
+	info, err := r.Fs.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file '%s': %w", path, err)
+	}
+
+	rendered, err := templates.Render(filepath.Base(path), string(content), r.vars)
+	if err != nil {
+		return fmt.Errorf("failed to render file '%s': %w", path, err)
+	}
+
+	if err := afero.WriteFile(r.rendered, filepath.Clean(path), []byte(rendered), info.Mode()); err != nil {
+		return fmt.Errorf("failed to store rendered file '%s': %w", path, err)
+	}
+	return nil
+}
+
+func (r *renderingFs) isRendered(name string) bool {
+	info, err := r.rendered.Stat(filepath.Clean(name))
+	return err == nil && !info.IsDir()
+}
+
+func (r *renderingFs) Open(name string) (afero.File, error) {
+	if r.isRendered(name) {
+		return r.rendered.Open(filepath.Clean(name))
+	}
+	return r.Fs.Open(name)
+}
+
+func (r *renderingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if !utils.HasWriteFlag(flag) && r.isRendered(name) {
+		return r.rendered.OpenFile(filepath.Clean(name), flag, perm)
+	}
+	return r.Fs.OpenFile(name, flag, perm)
+}
+
+func (r *renderingFs) Stat(name string) (os.FileInfo, error) {
+	if r.isRendered(name) {
+		return r.rendered.Stat(filepath.Clean(name))
+	}
+	return r.Fs.Stat(name)
+}
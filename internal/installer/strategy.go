@@ -2,6 +2,7 @@ package installer
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/hubblew/pim/internal/config"
 	"github.com/hubblew/pim/internal/utils"
@@ -12,29 +13,61 @@ import (
 //
 // Each strategy must implement methods to Initialize the output, AddFile to add files,
 // and Close any resources when done.
+//
+// Initialize receives the files written by the previous installation of the target. Only those
+// may be removed or overwritten freely; any other existing file requires confirmation of the prompter.
 type Strategy interface {
-	Initialize(prompter UserPrompter) error
-	AddFile(srcPath, relativePath string) error
+	Initialize(prompter UserPrompter, owned []string) error
+	AddFile(srcPath, relativePath string, origin FileOrigin) error
 	Close() error
 }
 
+// FileOrigin identifies the source an added file comes from.
+type FileOrigin struct {
+	// Source is the name of the source.
+	Source string
+	// Revision is the locked revision of the source, empty for local sources.
+	Revision string
+	// Local reports whether the source is a local directory, whose files may be linked instead of copied.
+	Local bool
+}
+
+// Path returns the path of the file in include format, e.g. "@source/path/to/file.md".
+func (o FileOrigin) Path(relativePath string) string {
+	relativePath = filepath.ToSlash(relativePath)
+	if o.Source == "" || o.Source == config.DefaultSourceName {
+		return relativePath
+	}
+	return "@" + o.Source + "/" + relativePath
+}
+
 func NewStrategy(
 	fs afero.Fs,
 	strategyType config.StrategyType,
 	outputPath string,
+	concatOptions *config.ConcatOptions,
+	link config.LinkMode,
 ) (Strategy, error) {
 	switch strategyType {
 	case config.StrategyConcat:
-		return NewConcatStrategy(fs, outputPath), nil
+		return NewConcatStrategy(fs, outputPath, concatOptions), nil
 	case config.StrategyFlatten:
-		return NewFlattenStrategy(fs, outputPath), nil
+		return NewFlattenStrategy(fs, outputPath, link), nil
 	case config.StrategyPreserve:
-		return NewPreserveStrategy(fs, outputPath), nil
+		return NewPreserveStrategy(fs, outputPath, link), nil
+	case config.StrategyCursorRules:
+		return NewCursorRulesStrategy(fs, outputPath), nil
+	case config.StrategyCopilotInstructions:
+		return NewCopilotInstructionsStrategy(fs, outputPath), nil
+	case config.StrategyWindsurfRules:
+		return NewWindsurfRulesStrategy(fs, outputPath), nil
+	case config.StrategyClineRules:
+		return NewClineRulesStrategy(fs, outputPath), nil
 	case "":
 		if utils.HasMdExtension(outputPath) {
-			return NewStrategy(fs, config.StrategyConcat, outputPath)
+			return NewStrategy(fs, config.StrategyConcat, outputPath, concatOptions, link)
 		} else {
-			return NewStrategy(fs, config.StrategyFlatten, outputPath)
+			return NewStrategy(fs, config.StrategyFlatten, outputPath, concatOptions, link)
 		}
 	}
 
@@ -1,31 +1,50 @@
 package installer
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/goccy/go-yaml"
+	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/lockfile"
+	"github.com/hubblew/pim/internal/utils"
 	"github.com/spf13/afero"
 )
 
+// ConcatStrategy concatenates all included files into a single output file.
+// The frontmatter of the included files is removed, and selected keys may be merged into
+// the header of the output file.
 type ConcatStrategy struct {
-	fs         afero.Fs
-	outputPath string
-	outFile    afero.File
+	fs          afero.Fs
+	outputPath  string
+	options     config.ConcatOptions
+	initialized bool
+	merged      map[string]any
+	body        bytes.Buffer
+	headings    []utils.Heading
+	files       int
 }
 
 var _ Strategy = (*ConcatStrategy)(nil)
 
-func NewConcatStrategy(fs afero.Fs, path string) *ConcatStrategy {
-	return &ConcatStrategy{
+func NewConcatStrategy(fs afero.Fs, path string, options *config.ConcatOptions) *ConcatStrategy {
+	strategy := &ConcatStrategy{
 		fs:         fs,
 		outputPath: path,
+		merged:     make(map[string]any),
 	}
+	if options != nil {
+		strategy.options = *options
+	}
+	return strategy
 }
 
-func (s *ConcatStrategy) Initialize(prompter UserPrompter) error {
-	if _, err := s.fs.Stat(s.outputPath); err == nil {
+func (s *ConcatStrategy) Initialize(prompter UserPrompter, owned []string) error {
+	if _, err := s.fs.Stat(s.outputPath); err == nil && !slices.ContainsFunc(owned, s.isOutputPath) {
 		isGeneratedByPim, err := IsPimGenerated(s.fs, s.outputPath)
 		if err != nil {
 			return fmt.Errorf("failed to check if file can be overridden: %w", err)
@@ -49,42 +68,173 @@ func (s *ConcatStrategy) Initialize(prompter UserPrompter) error {
 		return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(s.outputPath), err)
 	}
 
-	outFile, err := s.fs.Create(s.outputPath)
+	s.initialized = true
+	return nil
+}
+
+func (s *ConcatStrategy) isOutputPath(path string) bool {
+	return filepath.Clean(filepath.FromSlash(path)) == filepath.Clean(s.outputPath)
+}
+
+func (s *ConcatStrategy) AddFile(srcPath, relativePath string, origin FileOrigin) error {
+	content, err := afero.ReadFile(s.fs, srcPath)
 	if err != nil {
-		return fmt.Errorf("failed to create output file '%s': %w", s.outputPath, err)
+		return fmt.Errorf("failed to read source file '%s': %w", srcPath, err)
 	}
 
-	s.outFile = outFile
+	if s.options.Frontmatter == config.FrontmatterMerge {
+		if err := s.mergeFrontmatter(srcPath); err != nil {
+			return err
+		}
+	}
 
-	err = AddGeneratedByPimHeader(s.outFile)
-	if err != nil {
-		return fmt.Errorf("failed to write frontmatter to output file '%s': %w", s.outputPath, err)
+	body := utils.StripFrontmatter(content)
+	if len(body) != len(content) {
+		body = bytes.TrimLeft(body, "\r\n")
+	}
+	body = s.normalizeHeadings(body)
+
+	if s.files > 0 && s.options.Separator != "" {
+		s.body.WriteString(s.options.Separator + "\n\n")
+	}
+
+	start := s.body.Len()
+	s.writeAttribution(relativePath, origin)
+	s.body.Write(body)
+	s.body.WriteString("\n")
+	s.files++
+
+	if s.options.TOC {
+		s.headings = append(s.headings, utils.MarkdownHeadings(s.body.Bytes()[start:])...)
 	}
 
 	return nil
 }
 
-func (s *ConcatStrategy) AddFile(srcPath, _ string) error {
-	srcFile, err := s.fs.Open(srcPath)
-	if err != nil {
-		return fmt.Errorf("failed to open source file '%s': %w", srcPath, err)
+// writeAttribution writes the origin of the next included file in the configured style.
+func (s *ConcatStrategy) writeAttribution(relativePath string, origin FileOrigin) {
+	path := origin.Path(relativePath)
+
+	switch s.options.Attribution {
+	case config.AttributionComment:
+		if origin.Revision != "" {
+			_, _ = fmt.Fprintf(&s.body, "<!-- source: %s, revision: %s -->\n", path, origin.Revision)
+		} else {
+			_, _ = fmt.Fprintf(&s.body, "<!-- source: %s -->\n", path)
+		}
+	case config.AttributionHeading:
+		// The attribution heading encloses the headings of the file when they are normalized.
+		hashes := strings.Repeat("#", max(s.options.HeadingLevel-1, 1))
+		if origin.Revision != "" {
+			_, _ = fmt.Fprintf(&s.body, "%s %s (%s)\n\n", hashes, path, lockfile.ShortRevision(origin.Revision))
+		} else {
+			_, _ = fmt.Fprintf(&s.body, "%s %s\n\n", hashes, path)
+		}
 	}
-	defer srcFile.Close()
+}
 
-	if _, err := io.Copy(s.outFile, srcFile); err != nil {
-		return fmt.Errorf("failed to copy file '%s': %w", srcPath, err)
+// normalizeHeadings shifts the headings of an included file so its top-level headings have the configured level.
+func (s *ConcatStrategy) normalizeHeadings(body []byte) []byte {
+	if s.options.HeadingLevel == 0 {
+		return body
 	}
 
-	if _, err := s.outFile.WriteString("\n"); err != nil {
-		return fmt.Errorf("failed to write newline to output file '%s': %w", s.outputPath, err)
+	headings := utils.MarkdownHeadings(body)
+	if len(headings) == 0 {
+		return body
+	}
+
+	topLevel := headings[0].Level
+	for _, heading := range headings {
+		topLevel = min(topLevel, heading.Level)
+	}
+	return utils.ShiftHeadings(body, s.options.HeadingLevel-topLevel)
+}
+
+// mergeFrontmatter records the values of the merge keys that are not yet defined by a previous file.
+func (s *ConcatStrategy) mergeFrontmatter(srcPath string) error {
+	var frontmatter map[string]any
+	if err := utils.ReadFrontmatter(s.fs, srcPath, &frontmatter); err != nil {
+		return fmt.Errorf("failed to read frontmatter of '%s': %w", srcPath, err)
+	}
+
+	for _, key := range s.options.MergeKeys {
+		if _, ok := s.merged[key]; ok {
+			continue
+		}
+		if value, ok := frontmatter[key]; ok {
+			s.merged[key] = value
+		}
 	}
 
 	return nil
 }
 
+// header returns the merged frontmatter fields in the order of the merge keys.
+func (s *ConcatStrategy) header() yaml.MapSlice {
+	var fields yaml.MapSlice
+	for _, key := range s.options.MergeKeys {
+		if value, ok := s.merged[key]; ok && key != generatedByKey {
+			fields = append(fields, yaml.MapItem{Key: key, Value: value})
+		}
+	}
+	return fields
+}
+
+// tableOfContents returns a nested list linking to the included headings up to the maximum depth.
+func (s *ConcatStrategy) tableOfContents() []byte {
+	maxDepth := s.options.TOCMaxDepth
+	if maxDepth == 0 {
+		maxDepth = config.DefaultTOCMaxDepth
+	}
+
+	minLevel := maxDepth
+	for _, heading := range s.headings {
+		minLevel = min(minLevel, heading.Level)
+	}
+
+	var toc bytes.Buffer
+	anchors := utils.HeadingAnchors(s.headings)
+	for index, heading := range s.headings {
+		if heading.Level > maxDepth {
+			continue
+		}
+		indent := strings.Repeat("  ", heading.Level-minLevel)
+		_, _ = fmt.Fprintf(&toc, "%s- [%s](#%s)\n", indent, heading.Text, anchors[index])
+	}
+
+	if toc.Len() > 0 {
+		toc.WriteString("\n")
+	}
+	return toc.Bytes()
+}
+
 func (s *ConcatStrategy) Close() error {
-	if s.outFile != nil {
-		return s.outFile.Close()
+	if !s.initialized {
+		return nil
 	}
-	return nil
+
+	outFile, err := s.fs.Create(s.outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", s.outputPath, err)
+	}
+	defer func() {
+		_ = outFile.Close()
+	}()
+
+	if err := addPimHeader(outFile, s.header()); err != nil {
+		return fmt.Errorf("failed to write frontmatter to output file '%s': %w", s.outputPath, err)
+	}
+
+	if s.options.TOC {
+		if _, err := outFile.Write(s.tableOfContents()); err != nil {
+			return fmt.Errorf("failed to write table of contents to output file '%s': %w", s.outputPath, err)
+		}
+	}
+
+	if _, err := outFile.Write(s.body.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output file '%s': %w", s.outputPath, err)
+	}
+
+	return outFile.Close()
 }
@@ -1,10 +1,14 @@
 package installer
 
 import (
+	"io"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/hubblew/pim/internal/config"
+	"github.com/hubblew/pim/internal/utils"
 	"github.com/spf13/afero"
 )
 
@@ -21,8 +25,11 @@ func TestConcatStrategyIntegration(t *testing.T) {
 		name              string
 		files             []struct{ path, content string }
 		outputPath        string
+		options           *config.ConcatOptions
+		origin            FileOrigin
 		expectError       bool
 		expectedFragments []string
+		expectedOutput    string
 	}{
 		{
 			name: "concatenate multiple files",
@@ -50,6 +57,129 @@ func TestConcatStrategyIntegration(t *testing.T) {
 				"# Single File\nSingle content",
 			},
 		},
+		{
+			name: "strip frontmatter",
+			files: []struct{ path, content string }{
+				{"file1.md", "---\ndescription: First\napplyTo: '**/*.go'\n---\n\n# File 1\n"},
+				{"file2.md", "# File 2\n---\nnot: frontmatter\n---\n"},
+			},
+			outputPath: "output.md",
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"# File 1\n\n" +
+				"# File 2\n---\nnot: frontmatter\n---\n\n",
+		},
+		{
+			name: "merge frontmatter keys",
+			files: []struct{ path, content string }{
+				{"file1.md", "---\ndescription: First\nowner: team-a\n---\n# File 1\n"},
+				{"file2.md", "---\ndescription: Second\napplyTo: '**/*.go'\n---\n# File 2\n"},
+				{"file3.md", "# File 3\n"},
+			},
+			outputPath: "output.md",
+			options: &config.ConcatOptions{
+				Frontmatter: config.FrontmatterMerge,
+				MergeKeys:   []string{"applyTo", "description", "generatedBy", "missing"},
+			},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\napplyTo: \"**/*.go\"\ndescription: First\n---\n\n" +
+				"# File 1\n\n" +
+				"# File 2\n\n" +
+				"# File 3\n\n",
+		},
+		{
+			name: "comment attribution with separator",
+			files: []struct{ path, content string }{
+				{"docs/file1.md", "# File 1\n"},
+				{"docs/file2.md", "# File 2\n"},
+			},
+			outputPath: "output.md",
+			options: &config.ConcatOptions{
+				Attribution: config.AttributionComment,
+				Separator:   "---",
+			},
+			origin: FileOrigin{Source: "org", Revision: "3f1c2e9d4b5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d"},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"<!-- source: @org/docs/file1.md, revision: 3f1c2e9d4b5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d -->\n# File 1\n\n" +
+				"---\n\n" +
+				"<!-- source: @org/docs/file2.md, revision: 3f1c2e9d4b5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d -->\n# File 2\n\n",
+		},
+		{
+			name: "heading attribution of local files",
+			files: []struct{ path, content string }{
+				{"docs/file1.md", "Content 1\n"},
+				{"docs/file2.md", "Content 2\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{Attribution: config.AttributionHeading},
+			origin:     FileOrigin{Source: config.DefaultSourceName},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"# docs/file1.md\n\nContent 1\n\n" +
+				"# docs/file2.md\n\nContent 2\n\n",
+		},
+		{
+			name: "heading attribution of locked source",
+			files: []struct{ path, content string }{
+				{"file1.md", "Content 1\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{Attribution: config.AttributionHeading},
+			origin:     FileOrigin{Source: "org", Revision: "3f1c2e9d4b5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d"},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"# @org/file1.md (3f1c2e9d4b5a)\n\nContent 1\n\n",
+		},
+		{
+			name: "table of contents",
+			files: []struct{ path, content string }{
+				{"file1.md", "# Setup\n\n## Install\n\n```sh\n# not a heading\n```\n\n### Details\n"},
+				{"file2.md", "---\ndescription: Usage\n---\n# Usage\n\n## Install\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{TOC: true, TOCMaxDepth: 2},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"- [Setup](#setup)\n" +
+				"  - [Install](#install)\n" +
+				"- [Usage](#usage)\n" +
+				"  - [Install](#install-1)\n\n" +
+				"# Setup\n\n## Install\n\n```sh\n# not a heading\n```\n\n### Details\n\n" +
+				"# Usage\n\n## Install\n\n",
+		},
+		{
+			name: "table of contents with attribution headings",
+			files: []struct{ path, content string }{
+				{"file1.md", "## Rules\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{TOC: true, Attribution: config.AttributionHeading},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"- [file1.md](#file1md)\n" +
+				"  - [Rules](#rules)\n\n" +
+				"# file1.md\n\n## Rules\n\n",
+		},
+		{
+			name: "normalize heading levels",
+			files: []struct{ path, content string }{
+				{"file1.md", "# Style\n\n## Naming\n\n```md\n# Example\n```\n"},
+				{"file2.md", "### Testing\n\n#### Tables\n"},
+				{"file3.md", "No headings\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{HeadingLevel: 2},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"## Style\n\n### Naming\n\n```md\n# Example\n```\n\n" +
+				"## Testing\n\n### Tables\n\n" +
+				"No headings\n\n",
+		},
+		{
+			name: "normalize heading levels below attribution headings",
+			files: []struct{ path, content string }{
+				{"file1.md", "# Style\n"},
+			},
+			outputPath: "output.md",
+			options:    &config.ConcatOptions{HeadingLevel: 3, Attribution: config.AttributionHeading, TOC: true},
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n" +
+				"- [file1.md](#file1md)\n" +
+				"  - [Style](#style)\n\n" +
+				"## file1.md\n\n### Style\n\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -62,10 +192,10 @@ func TestConcatStrategyIntegration(t *testing.T) {
 				}
 			}
 
-			strategy := NewConcatStrategy(fs, tt.outputPath)
+			strategy := NewConcatStrategy(fs, tt.outputPath, tt.options)
 			prompter := &mockPrompter{allowOverwrite: true}
 
-			if err := strategy.Initialize(prompter); err != nil {
+			if err := strategy.Initialize(prompter, nil); err != nil {
 				if !tt.expectError {
 					t.Fatalf("unexpected error on Initialize: %v", err)
 				}
@@ -73,7 +203,7 @@ func TestConcatStrategyIntegration(t *testing.T) {
 			}
 
 			for _, f := range tt.files {
-				if err := strategy.AddFile(f.path, f.path); err != nil {
+				if err := strategy.AddFile(f.path, f.path, tt.origin); err != nil {
 					if !tt.expectError {
 						t.Fatalf("unexpected error on AddFile: %v", err)
 					}
@@ -91,6 +221,9 @@ func TestConcatStrategyIntegration(t *testing.T) {
 			}
 
 			outputStr := string(output)
+			if tt.expectedOutput != "" && outputStr != tt.expectedOutput {
+				t.Errorf("unexpected output\nexpected:\n%q\ngot:\n%q", tt.expectedOutput, outputStr)
+			}
 			for _, fragment := range tt.expectedFragments {
 				if !strings.Contains(outputStr, fragment) {
 					t.Errorf("output missing expected fragment:\n%s\n\nFull output:\n%s", fragment, outputStr)
@@ -118,15 +251,15 @@ func TestFlattenStrategyIntegration(t *testing.T) {
 		}
 	}
 
-	strategy := NewFlattenStrategy(fs, "output")
+	strategy := NewFlattenStrategy(fs, "output", config.LinkCopy)
 	prompter := &mockPrompter{allowOverwrite: true}
 
-	if err := strategy.Initialize(prompter); err != nil {
+	if err := strategy.Initialize(prompter, nil); err != nil {
 		t.Fatalf("failed to initialize: %v", err)
 	}
 
 	for path := range files {
-		if err := strategy.AddFile(path, path); err != nil {
+		if err := strategy.AddFile(path, path, FileOrigin{}); err != nil {
 			t.Fatalf("failed to add file %s: %v", path, err)
 		}
 	}
@@ -171,15 +304,15 @@ func TestPreserveStrategyIntegration(t *testing.T) {
 		}
 	}
 
-	strategy := NewPreserveStrategy(fs, "output")
+	strategy := NewPreserveStrategy(fs, "output", config.LinkCopy)
 	prompter := &mockPrompter{allowOverwrite: true}
 
-	if err := strategy.Initialize(prompter); err != nil {
+	if err := strategy.Initialize(prompter, nil); err != nil {
 		t.Fatalf("failed to initialize: %v", err)
 	}
 
 	for path := range files {
-		if err := strategy.AddFile(path, path); err != nil {
+		if err := strategy.AddFile(path, path, FileOrigin{}); err != nil {
 			t.Fatalf("failed to add file %s: %v", path, err)
 		}
 	}
@@ -208,3 +341,533 @@ func TestPreserveStrategyIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestPreserveStrategyOnlyRemovesOwnedFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"src/new.md":             "new",
+		"src/conflict.md":        "conflict",
+		"output/owned.md":        "owned",
+		"output/nested/owned.md": "owned",
+		"output/unmanaged.md":    "unmanaged",
+		"output/conflict.md":     "unmanaged",
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	owned := []string{"output/owned.md", "output/nested/owned.md", "elsewhere.md"}
+
+	tests := []struct {
+		name           string
+		allowOverwrite bool
+		expectError    bool
+	}{
+		{"declined overwrite", false, true},
+		{"confirmed overwrite", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := utils.NewOverlayFs(fs)
+			strategy := NewPreserveStrategy(fs, "output", config.LinkCopy)
+
+			if err := strategy.Initialize(&mockPrompter{allowOverwrite: tt.allowOverwrite}, owned); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+
+			if err := strategy.AddFile("src/new.md", "new.md", FileOrigin{}); err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			err := strategy.AddFile("src/conflict.md", "conflict.md", FileOrigin{})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+
+			for _, path := range []string{"output/owned.md", "output/nested"} {
+				if exists, _ := afero.Exists(fs, path); exists {
+					t.Errorf("expected owned path %s to be removed", path)
+				}
+			}
+
+			content, _ := afero.ReadFile(fs, "output/unmanaged.md")
+			if string(content) != "unmanaged" {
+				t.Errorf("expected unmanaged file to be kept, got %q", content)
+			}
+
+			expectedConflict := "unmanaged"
+			if tt.allowOverwrite {
+				expectedConflict = "conflict"
+			}
+			content, _ = afero.ReadFile(fs, "output/conflict.md")
+			if string(content) != expectedConflict {
+				t.Errorf("expected conflicting file content %q, got %q", expectedConflict, content)
+			}
+		})
+	}
+}
+
+func TestCursorRulesStrategyIntegration(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		content        string
+		expectedPath   string
+		expectedOutput string
+		expectError    string
+	}{
+		{
+			name:           "scoped instructions",
+			path:           "instructions/go.instructions.md",
+			content:        "---\ndescription: Go conventions\napplyTo: '**/*.go, ./cmd/**'\n---\n\n# Go\n",
+			expectedPath:   "rules/go.mdc",
+			expectedOutput: "---\ndescription: Go conventions\nglobs: **/*.go,cmd/**\nalwaysApply: false\n---\n\n# Go\n",
+		},
+		{
+			name:           "list of patterns",
+			path:           "testing.md",
+			content:        "---\napplyTo:\n  - '**/*_test.go'\n  - '**/testdata/**'\n---\n# Testing\n",
+			expectedPath:   "rules/testing.mdc",
+			expectedOutput: "---\nglobs: **/*_test.go,**/testdata/**\nalwaysApply: false\n---\n\n# Testing\n",
+		},
+		{
+			name:           "instructions for all files",
+			path:           "style.md",
+			content:        "---\ndescription: 'Style: general'\napplyTo: '**'\n---\n# Style\n",
+			expectedPath:   "rules/style.mdc",
+			expectedOutput: "---\ndescription: \"Style: general\"\nalwaysApply: true\n---\n\n# Style\n",
+		},
+		{
+			name:           "without frontmatter",
+			path:           "intro.md",
+			content:        "# Intro\n",
+			expectedPath:   "rules/intro.mdc",
+			expectedOutput: "---\nalwaysApply: true\n---\n\n# Intro\n",
+		},
+		{
+			name:        "invalid applyTo",
+			path:        "broken.md",
+			content:     "---\napplyTo: 'src/[a-'\n---\n# Broken\n",
+			expectError: "invalid applyTo pattern: src/[a-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, tt.path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+
+			strategy := NewCursorRulesStrategy(fs, "rules")
+			if err := strategy.Initialize(&mockPrompter{}, nil); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+
+			err := strategy.AddFile(tt.path, tt.path, FileOrigin{})
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			if err := strategy.Close(); err != nil {
+				t.Fatalf("failed to close: %v", err)
+			}
+
+			content, err := afero.ReadFile(fs, tt.expectedPath)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.expectedPath, err)
+			}
+			if string(content) != tt.expectedOutput {
+				t.Errorf("content mismatch\nexpected: %q\ngot: %q", tt.expectedOutput, string(content))
+			}
+		})
+	}
+}
+
+func TestCopilotInstructionsStrategyIntegration(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		content        string
+		existing       string
+		expectedPath   string
+		expectedOutput string
+		expectError    string
+	}{
+		{
+			name:         "normalized applyTo",
+			path:         "instructions/go.md",
+			content:      "---\ndescription: Go conventions\napplyTo: ' **/*.go , .\\cmd\\**,'\nmode: agent\n---\n\n# Go\n",
+			expectedPath: "out/go.instructions.md",
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\ndescription: Go conventions\napplyTo: \"**/*.go,cmd/**\"\n---\n\n" +
+				"# Go\n",
+		},
+		{
+			name:           "instructions without applyTo apply to all files",
+			path:           "testing.instructions.md",
+			content:        "# Testing\n",
+			expectedPath:   "out/testing.instructions.md",
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\napplyTo: \"**\"\n---\n\n# Testing\n",
+		},
+		{
+			name:           "overwrites generated file",
+			path:           "style.md",
+			content:        "# Style\n",
+			existing:       "---\ngeneratedBy: github.com/hubblew/pim-cli\n---\n\n# Old style\n",
+			expectedPath:   "out/style.instructions.md",
+			expectedOutput: "---\ngeneratedBy: github.com/hubblew/pim-cli\napplyTo: \"**\"\n---\n\n# Style\n",
+		},
+		{
+			name:         "asks before overwriting a manual file",
+			path:         "style.md",
+			content:      "# Style\n",
+			existing:     "# Written by hand\n",
+			expectedPath: "out/style.instructions.md",
+			expectError:  "user declined to override file 'out/style.instructions.md'",
+		},
+		{
+			name:        "invalid applyTo",
+			path:        "broken.md",
+			content:     "---\napplyTo: /abs/**\n---\n# Broken\n",
+			expectError: "invalid applyTo pattern: /abs/**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, tt.path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+			if tt.existing != "" {
+				if err := afero.WriteFile(fs, tt.expectedPath, []byte(tt.existing), 0644); err != nil {
+					t.Fatalf("failed to create existing file: %v", err)
+				}
+			}
+
+			strategy := NewCopilotInstructionsStrategy(fs, "out")
+			if err := strategy.Initialize(&mockPrompter{allowOverwrite: false}, nil); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+
+			err := strategy.AddFile(tt.path, tt.path, FileOrigin{})
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			if err := strategy.Close(); err != nil {
+				t.Fatalf("failed to close: %v", err)
+			}
+
+			content, err := afero.ReadFile(fs, tt.expectedPath)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.expectedPath, err)
+			}
+			if string(content) != tt.expectedOutput {
+				t.Errorf("content mismatch\nexpected: %q\ngot: %q", tt.expectedOutput, string(content))
+			}
+
+			generated, err := IsPimGenerated(fs, tt.expectedPath)
+			if err != nil || !generated {
+				t.Errorf("expected %s to be marked as generated by PIM, got %v (%v)", tt.expectedPath, generated, err)
+			}
+		})
+	}
+}
+
+func TestAgentRulesStrategyIntegration(t *testing.T) {
+	tests := []struct {
+		name           string
+		newStrategy    func(fs afero.Fs, path string) *RulesStrategy
+		content        string
+		expectedPath   string
+		expectedOutput string
+	}{
+		{
+			name:           "windsurf scoped rule",
+			newStrategy:    NewWindsurfRulesStrategy,
+			content:        "---\ndescription: Go conventions\napplyTo: '**/*.go,cmd/**'\n---\n\n# Go\n",
+			expectedPath:   "rules/go.md",
+			expectedOutput: "---\ntrigger: glob\ndescription: Go conventions\nglobs: **/*.go,cmd/**\n---\n\n# Go\n",
+		},
+		{
+			name:           "windsurf rule for all files",
+			newStrategy:    NewWindsurfRulesStrategy,
+			content:        "# Go\n",
+			expectedPath:   "rules/go.md",
+			expectedOutput: "---\ntrigger: always_on\n---\n\n# Go\n",
+		},
+		{
+			name:           "cline scoped rule",
+			newStrategy:    NewClineRulesStrategy,
+			content:        "---\ndescription: Go conventions\napplyTo: '**/*.go,cmd/**'\n---\n\n# Go\n",
+			expectedPath:   "rules/go.md",
+			expectedOutput: "---\npaths:\n- \"**/*.go\"\n- cmd/**\n---\n\n# Go\n",
+		},
+		{
+			name:           "cline rule for all files",
+			newStrategy:    NewClineRulesStrategy,
+			content:        "---\ndescription: Go conventions\napplyTo: '**'\n---\n\n# Go\n",
+			expectedPath:   "rules/go.md",
+			expectedOutput: "# Go\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, "instructions/go.instructions.md", []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+
+			strategy := tt.newStrategy(fs, "rules")
+			if err := strategy.Initialize(&mockPrompter{}, nil); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+			if err := strategy.AddFile("instructions/go.instructions.md", "go.instructions.md", FileOrigin{}); err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			if err := strategy.Close(); err != nil {
+				t.Fatalf("failed to close: %v", err)
+			}
+
+			content, err := afero.ReadFile(fs, tt.expectedPath)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.expectedPath, err)
+			}
+			if string(content) != tt.expectedOutput {
+				t.Errorf("content mismatch\nexpected: %q\ngot: %q", tt.expectedOutput, string(content))
+			}
+		})
+	}
+}
+
+func TestStrategiesRejectConflictingFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		newStrategy func(fs afero.Fs, path string) Strategy
+		expectError string
+	}{
+		{
+			name:        "flatten",
+			newStrategy: func(fs afero.Fs, path string) Strategy { return NewFlattenStrategy(fs, path, config.LinkCopy) },
+			expectError: "'i/x/go.md' and 'i/y/go.md' are both installed to '" + filepath.Join("output", "go.md") + "'",
+		},
+		{
+			name:        "cursor rules",
+			newStrategy: func(fs afero.Fs, path string) Strategy { return NewCursorRulesStrategy(fs, path) },
+			expectError: "'i/x/go.md' and 'i/y/go.md' are both installed to '" + filepath.Join("output", "go.mdc") + "'",
+		},
+		{
+			name:        "copilot instructions",
+			newStrategy: func(fs afero.Fs, path string) Strategy { return NewCopilotInstructionsStrategy(fs, path) },
+			expectError: "are both installed to '" + filepath.Join("output", "go.instructions.md") + "'",
+		},
+		{
+			name:        "windsurf rules",
+			newStrategy: func(fs afero.Fs, path string) Strategy { return NewWindsurfRulesStrategy(fs, path) },
+			expectError: "are both installed to '" + filepath.Join("output", "go.md") + "'",
+		},
+		{
+			name:        "cline rules",
+			newStrategy: func(fs afero.Fs, path string) Strategy { return NewClineRulesStrategy(fs, path) },
+			expectError: "are both installed to '" + filepath.Join("output", "go.md") + "'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for _, path := range []string{"i/x/go.md", "i/y/go.md"} {
+				if err := afero.WriteFile(fs, path, []byte("# "+path+"\n"), 0644); err != nil {
+					t.Fatalf("failed to create file: %v", err)
+				}
+			}
+
+			strategy := tt.newStrategy(fs, "output")
+			if err := strategy.Initialize(&mockPrompter{}, nil); err != nil {
+				t.Fatalf("failed to initialize: %v", err)
+			}
+			// The same file may be included twice.
+			for _, path := range []string{"i/x/go.md", "i/x/go.md"} {
+				if err := strategy.AddFile(path, path, FileOrigin{}); err != nil {
+					t.Fatalf("failed to add file %s: %v", path, err)
+				}
+			}
+
+			err := strategy.AddFile("i/y/go.md", "i/y/go.md", FileOrigin{})
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestGlobFilesNestedTree(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	for _, path := range []string{
+		"src/README.md",
+		"src/guide.txt",
+		"src/docs/intro.md",
+		"src/docs/b/setup.md",
+		"src/docs/a/deep/nested/usage.md",
+		"src/docs/a/notes.txt",
+		"src/prompts/review.md",
+		"shared/rules.md",
+		"shared/nested/style.md",
+	} {
+		if err := afero.WriteFile(fs, path, []byte(path), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "literal path",
+			pattern:  "docs/intro.md",
+			expected: []string{"docs/intro.md"},
+		},
+		{
+			name:     "single level wildcard",
+			pattern:  "docs/*.md",
+			expected: []string{"docs/intro.md"},
+		},
+		{
+			name:    "recursive wildcard",
+			pattern: "docs/**/*.md",
+			expected: []string{
+				"docs/a/deep/nested/usage.md",
+				"docs/b/setup.md",
+				"docs/intro.md",
+			},
+		},
+		{
+			name:    "recursive wildcard from root",
+			pattern: "**/*.md",
+			expected: []string{
+				"README.md",
+				"docs/a/deep/nested/usage.md",
+				"docs/b/setup.md",
+				"docs/intro.md",
+				"prompts/review.md",
+			},
+		},
+		{
+			name:    "recursive wildcard matches files only",
+			pattern: "docs/**",
+			expected: []string{
+				"docs/a/deep/nested/usage.md",
+				"docs/a/notes.txt",
+				"docs/b/setup.md",
+				"docs/intro.md",
+			},
+		},
+		{
+			name:     "no match",
+			pattern:  "**/*.yaml",
+			expected: nil,
+		},
+		{
+			name:     "literal path outside the directory",
+			pattern:  "../shared/rules.md",
+			expected: []string{"../shared/rules.md"},
+		},
+		{
+			name:     "recursive wildcard outside the directory",
+			pattern:  "../shared/**/*.md",
+			expected: []string{"../shared/nested/style.md", "../shared/rules.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := globFiles(fs, "src", tt.pattern)
+			if err != nil {
+				t.Fatalf("globFiles() error = %v", err)
+			}
+
+			var expected []string
+			for _, path := range tt.expected {
+				expected = append(expected, filepath.FromSlash(path))
+			}
+			if !slices.Equal(matches, expected) {
+				t.Errorf("globFiles() = %v, expected %v", matches, expected)
+			}
+		})
+	}
+}
+
+func TestAddTargetFilesNestedTree(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	for _, path := range []string{
+		"src/docs/intro.md",
+		"src/docs/b/setup.md",
+		"src/docs/a/deep/usage.md",
+		"src/docs/a/deep/draft.md",
+		"src/docs/drafts/todo.md",
+	} {
+		if err := afero.WriteFile(fs, path, []byte(path), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	target := &config.Target{
+		Name:          "docs",
+		IncludeParsed: []config.Include{{Source: "local", File: "docs/**/*.md"}},
+		ExcludeParsed: []config.Include{
+			{Source: "local", File: "docs/drafts/**"},
+			{Source: "local", File: "**/draft.md"},
+		},
+	}
+
+	strategy := &recordingStrategy{}
+	installer := &Installer{fs: fs, out: io.Discard}
+	if err := addTargetFiles(installer, target, map[string]string{"local": "src"}, []Strategy{strategy}, nil); err != nil {
+		t.Fatalf("addTargetFiles() error = %v", err)
+	}
+
+	expected := []string{
+		filepath.FromSlash("docs/a/deep/usage.md"),
+		filepath.FromSlash("docs/b/setup.md"),
+		filepath.FromSlash("docs/intro.md"),
+	}
+	if !slices.Equal(strategy.added, expected) {
+		t.Errorf("added files = %v, expected %v", strategy.added, expected)
+	}
+}
+
+// recordingStrategy records the relative paths of the added files.
+type recordingStrategy struct {
+	added []string
+}
+
+func (s *recordingStrategy) Initialize(UserPrompter, []string) error {
+	return nil
+}
+
+func (s *recordingStrategy) AddFile(_, relativePath string, _ FileOrigin) error {
+	s.added = append(s.added, relativePath)
+	return nil
+}
+
+func (s *recordingStrategy) Close() error {
+	return nil
+}
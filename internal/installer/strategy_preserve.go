@@ -1,42 +1,37 @@
 package installer
 
 import (
-	"fmt"
-	"os"
 	"path/filepath"
 
-	"github.com/hubblew/pim/internal/utils"
+	"github.com/hubblew/pim/internal/config"
 	"github.com/spf13/afero"
 )
 
 type PreserveStrategy struct {
 	fs         afero.Fs
 	outputPath string
+	output     *managedOutput
 }
 
 var _ Strategy = (*PreserveStrategy)(nil)
 
-func NewPreserveStrategy(fs afero.Fs, path string) *PreserveStrategy {
+func NewPreserveStrategy(fs afero.Fs, path string, link config.LinkMode) *PreserveStrategy {
+	output := newManagedOutput(fs, path)
+	output.link = link
 	return &PreserveStrategy{
 		fs:         fs,
 		outputPath: path,
+		output:     output,
 	}
 }
 
-func (s *PreserveStrategy) Initialize(_ UserPrompter) error {
-	if err := s.fs.RemoveAll(s.outputPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete output directory '%s': %w", s.outputPath, err)
-	}
-
-	if err := s.fs.MkdirAll(s.outputPath, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory '%s': %w", s.outputPath, err)
-	}
-	return nil
+func (s *PreserveStrategy) Initialize(prompter UserPrompter, owned []string) error {
+	return s.output.initialize(prompter, owned)
 }
 
-func (s *PreserveStrategy) AddFile(srcPath, relativePath string) error {
+func (s *PreserveStrategy) AddFile(srcPath, relativePath string, origin FileOrigin) error {
 	dstPath := filepath.Join(s.outputPath, relativePath)
-	return utils.CopyFile(s.fs, srcPath, dstPath)
+	return s.output.installFile(srcPath, relativePath, dstPath, origin)
 }
 
 func (s *PreserveStrategy) Close() error {
@@ -0,0 +1,195 @@
+package installer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/afero"
+)
+
+// RulesStrategy converts each included file into a rule file of an agent in the output directory.
+// The description and applyTo frontmatter of the file are translated into the frontmatter dialect of the agent.
+type RulesStrategy struct {
+	fs         afero.Fs
+	outputPath string
+	output     *managedOutput
+	dialect    ruleDialect
+}
+
+var _ Strategy = (*RulesStrategy)(nil)
+
+// ruleDialect defines the file names and the frontmatter of the rule files of an agent.
+type ruleDialect struct {
+	// kind names a rule file in errors, e.g. "a Cursor rule".
+	kind string
+	// suffix replaces the extensions of the name of included files.
+	suffix string
+	// frontmatter returns the frontmatter block of a rule, nil for none.
+	// globs are the normalized applyTo patterns, empty when the rule applies to all files.
+	frontmatter func(description string, globs []string) ([]byte, error)
+	// generated rules start with the PIM generation marker and are overwritten without confirmation.
+	generated bool
+}
+
+// cursorRules are ".mdc" files with description, globs and alwaysApply.
+var cursorRules = ruleDialect{
+	kind:   "a Cursor rule",
+	suffix: ".mdc",
+	frontmatter: func(description string, globs []string) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := writeYAMLField(&buf, "description", description); err != nil {
+			return nil, err
+		}
+		// Cursor reads globs as a plain comma-separated list, which must not be quoted like a YAML string.
+		if len(globs) == 0 {
+			buf.WriteString("alwaysApply: true\n")
+		} else {
+			_, _ = fmt.Fprintf(&buf, "globs: %s\nalwaysApply: false\n", strings.Join(globs, ","))
+		}
+		return frontmatterBlock(buf.Bytes()), nil
+	},
+}
+
+// copilotInstructions are path-specific ".instructions.md" files with description and applyTo, which is "**"
+// for instructions that apply to all files.
+var copilotInstructions = ruleDialect{
+	kind:   "Copilot instructions",
+	suffix: ".instructions.md",
+	frontmatter: func(description string, globs []string) ([]byte, error) {
+		var fields yaml.MapSlice
+		if description != "" {
+			fields = append(fields, yaml.MapItem{Key: "description", Value: description})
+		}
+		applyTo := "**"
+		if len(globs) > 0 {
+			applyTo = strings.Join(globs, ",")
+		}
+		fields = append(fields, yaml.MapItem{Key: "applyTo", Value: applyTo})
+
+		var buf bytes.Buffer
+		if err := addPimHeader(&buf, fields); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+	generated: true,
+}
+
+// windsurfRules are ".md" files with a trigger, always_on or glob, description and globs.
+var windsurfRules = ruleDialect{
+	kind:   "a Windsurf rule",
+	suffix: ".md",
+	frontmatter: func(description string, globs []string) ([]byte, error) {
+		var buf bytes.Buffer
+		if len(globs) == 0 {
+			buf.WriteString("trigger: always_on\n")
+		} else {
+			buf.WriteString("trigger: glob\n")
+		}
+		if err := writeYAMLField(&buf, "description", description); err != nil {
+			return nil, err
+		}
+		// Like Cursor, Windsurf reads globs as a plain comma-separated list.
+		if len(globs) > 0 {
+			_, _ = fmt.Fprintf(&buf, "globs: %s\n", strings.Join(globs, ","))
+		}
+		return frontmatterBlock(buf.Bytes()), nil
+	},
+}
+
+// clineRules are ".md" files. Rules that apply to some files list their globs as paths, others have no frontmatter.
+var clineRules = ruleDialect{
+	kind:   "a Cline rule",
+	suffix: ".md",
+	frontmatter: func(_ string, globs []string) ([]byte, error) {
+		if len(globs) == 0 {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		if err := writeYAMLField(&buf, "paths", globs); err != nil {
+			return nil, err
+		}
+		return frontmatterBlock(buf.Bytes()), nil
+	},
+}
+
+func NewCursorRulesStrategy(fs afero.Fs, path string) *RulesStrategy {
+	return newRulesStrategy(fs, path, cursorRules)
+}
+
+func NewCopilotInstructionsStrategy(fs afero.Fs, path string) *RulesStrategy {
+	return newRulesStrategy(fs, path, copilotInstructions)
+}
+
+func NewWindsurfRulesStrategy(fs afero.Fs, path string) *RulesStrategy {
+	return newRulesStrategy(fs, path, windsurfRules)
+}
+
+func NewClineRulesStrategy(fs afero.Fs, path string) *RulesStrategy {
+	return newRulesStrategy(fs, path, clineRules)
+}
+
+func newRulesStrategy(fs afero.Fs, path string, dialect ruleDialect) *RulesStrategy {
+	output := newManagedOutput(fs, path)
+	output.overwriteGenerated = dialect.generated
+
+	return &RulesStrategy{
+		fs:         fs,
+		outputPath: path,
+		output:     output,
+		dialect:    dialect,
+	}
+}
+
+func (s *RulesStrategy) Initialize(prompter UserPrompter, owned []string) error {
+	return s.output.initialize(prompter, owned)
+}
+
+func (s *RulesStrategy) AddFile(srcPath, relativePath string, origin FileOrigin) error {
+	frontmatter, body, err := readInstruction(s.fs, srcPath)
+	if err != nil {
+		return err
+	}
+
+	globs, err := frontmatter.globs()
+	if err != nil {
+		return fmt.Errorf("failed to convert '%s' to %s: %w", srcPath, s.dialect.kind, err)
+	}
+	if appliesToAllFiles(globs) {
+		globs = nil
+	}
+
+	header, err := s.dialect.frontmatter(frontmatter.Description, globs)
+	if err != nil {
+		return fmt.Errorf("failed to convert '%s' to %s: %w", srcPath, s.dialect.kind, err)
+	}
+
+	rule := append(header, body...)
+	dstPath := filepath.Join(s.outputPath, instructionName(relativePath)+s.dialect.suffix)
+	return s.output.writeFile(dstPath, origin.Path(relativePath), rule)
+}
+
+func (s *RulesStrategy) Close() error {
+	return nil
+}
+
+// writeYAMLField writes "key: value" with the value marshaled as YAML. Empty values are omitted.
+func writeYAMLField(buf *bytes.Buffer, key string, value any) error {
+	if value == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(map[string]any{key: value})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	buf.Write(data)
+	return nil
+}
+
+// frontmatterBlock encloses fields in frontmatter delimiters, followed by an empty line.
+func frontmatterBlock(fields []byte) []byte {
+	return []byte("---\n" + string(fields) + "---\n\n")
+}
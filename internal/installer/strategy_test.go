@@ -37,6 +37,34 @@ func TestCreateStrategy(t *testing.T) {
 			expectError:  false,
 			expectedType: reflect.TypeOf(&PreserveStrategy{}),
 		},
+		{
+			name:         "explicit cursor-rules strategy",
+			strategyType: config.StrategyCursorRules,
+			outputPath:   "./.cursor/rules",
+			expectError:  false,
+			expectedType: reflect.TypeOf(&RulesStrategy{}),
+		},
+		{
+			name:         "explicit copilot-instructions strategy",
+			strategyType: config.StrategyCopilotInstructions,
+			outputPath:   "./.github/instructions",
+			expectError:  false,
+			expectedType: reflect.TypeOf(&RulesStrategy{}),
+		},
+		{
+			name:         "explicit windsurf-rules strategy",
+			strategyType: config.StrategyWindsurfRules,
+			outputPath:   "./.windsurf/rules",
+			expectError:  false,
+			expectedType: reflect.TypeOf(&RulesStrategy{}),
+		},
+		{
+			name:         "explicit cline-rules strategy",
+			strategyType: config.StrategyClineRules,
+			outputPath:   "./.clinerules",
+			expectError:  false,
+			expectedType: reflect.TypeOf(&RulesStrategy{}),
+		},
 		{
 			name:         "auto-detect concat from .md extension",
 			strategyType: "",
@@ -63,7 +91,7 @@ func TestCreateStrategy(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fs := afero.NewMemMapFs()
-			strategy, err := NewStrategy(fs, tt.strategyType, tt.outputPath)
+			strategy, err := NewStrategy(fs, tt.strategyType, tt.outputPath, nil, "")
 
 			if tt.expectError {
 				if err == nil {
@@ -107,7 +135,7 @@ func TestCreateStrategyOutputPaths(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fs := afero.NewMemMapFs()
-			strategy, err := NewStrategy(fs, "", tt.outputPath)
+			strategy, err := NewStrategy(fs, "", tt.outputPath, nil, "")
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -0,0 +1,83 @@
+package lockfile
+
+import (
+	"sort"
+)
+
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "added"
+	FileRemoved  FileStatus = "removed"
+	FileModified FileStatus = "modified"
+)
+
+// FileChange describes how an included file of a source changed between two lockfiles.
+type FileChange struct {
+	Path   string
+	Status FileStatus
+}
+
+// SourceChange describes how a source changed between two lockfiles.
+// An empty revision means that the source was not locked.
+type SourceChange struct {
+	Name        string
+	OldRevision string
+	NewRevision string
+	Files       []FileChange
+}
+
+// Changed reports whether the source revision or any of its included files changed.
+func (c *SourceChange) Changed() bool {
+	return c.OldRevision != c.NewRevision || len(c.Files) > 0
+}
+
+// Diff compares the given sources between the lockfile before and after an update.
+func Diff(before, after *Lockfile, names []string) []SourceChange {
+	changes := make([]SourceChange, 0, len(names))
+
+	for _, name := range names {
+		change := SourceChange{Name: name}
+
+		var oldFiles, newFiles map[string]string
+		if source := before.Source(name); source != nil {
+			change.OldRevision = source.Revision()
+			oldFiles = source.Files
+		}
+		if source := after.Source(name); source != nil {
+			change.NewRevision = source.Revision()
+			newFiles = source.Files
+		}
+
+		change.Files = diffFiles(oldFiles, newFiles)
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func diffFiles(oldFiles, newFiles map[string]string) []FileChange {
+	var changes []FileChange
+
+	for path, oldHash := range oldFiles {
+		newHash, ok := newFiles[path]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: path, Status: FileRemoved})
+		case newHash != oldHash:
+			changes = append(changes, FileChange{Path: path, Status: FileModified})
+		}
+	}
+
+	for path := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			changes = append(changes, FileChange{Path: path, Status: FileAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
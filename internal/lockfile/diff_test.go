@@ -0,0 +1,74 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := New()
+	before.Put(Source{
+		Name:   "lib",
+		Commit: "aaa",
+		Files: map[string]string{
+			"kept.md":     "sha256:1",
+			"modified.md": "sha256:2",
+			"removed.md":  "sha256:3",
+		},
+	})
+	before.Put(Source{
+		Name:   "stable",
+		Commit: "bbb",
+		Files:  map[string]string{"a.md": "sha256:4"},
+	})
+
+	after := New()
+	after.Put(Source{
+		Name:   "lib",
+		Commit: "ccc",
+		Files: map[string]string{
+			"kept.md":     "sha256:1",
+			"modified.md": "sha256:5",
+			"added.md":    "sha256:6",
+		},
+	})
+	after.Put(Source{
+		Name:   "stable",
+		Commit: "bbb",
+		Files:  map[string]string{"a.md": "sha256:4"},
+	})
+	after.Put(Source{
+		Name:     "fresh",
+		Checksum: "sha256:7",
+	})
+
+	changes := Diff(before, after, []string{"lib", "stable", "fresh"})
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+
+	lib := changes[0]
+	if lib.OldRevision != "aaa" || lib.NewRevision != "ccc" {
+		t.Errorf("unexpected revisions %q -> %q", lib.OldRevision, lib.NewRevision)
+	}
+	expectedFiles := []FileChange{
+		{Path: "added.md", Status: FileAdded},
+		{Path: "modified.md", Status: FileModified},
+		{Path: "removed.md", Status: FileRemoved},
+	}
+	if !reflect.DeepEqual(lib.Files, expectedFiles) {
+		t.Errorf("expected file changes %v, got %v", expectedFiles, lib.Files)
+	}
+	if !lib.Changed() {
+		t.Error("expected 'lib' to be changed")
+	}
+
+	if changes[1].Changed() {
+		t.Errorf("expected 'stable' to be unchanged, got %+v", changes[1])
+	}
+
+	fresh := changes[2]
+	if fresh.OldRevision != "" || fresh.NewRevision != "sha256:7" {
+		t.Errorf("unexpected revisions %q -> %q", fresh.OldRevision, fresh.NewRevision)
+	}
+}
@@ -0,0 +1,173 @@
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/afero"
+)
+
+const FileExtension = ".lock"
+
+const shortRevisionLength = 12
+
+const header = "# This file is generated by PIM. Do not edit it manually.\n\n"
+
+// Source is the locked state of a single fetched source.
+type Source struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Ref is the git ref requested by the source configuration.
+	Ref string `yaml:"ref,omitempty"`
+	// Version is the semver constraint requested by the source configuration.
+	Version string `yaml:"version,omitempty"`
+	// Tag is the git tag selected by the version constraint.
+	Tag string `yaml:"tag,omitempty"`
+	// Commit is the resolved git commit for git sources.
+	Commit string `yaml:"commit,omitempty"`
+	// Checksum is the digest of the fetched directory tree for non-git sources.
+	Checksum string `yaml:"checksum,omitempty"`
+	// Files maps paths of included files, relative to the source root, to their content hash.
+	Files map[string]string `yaml:"files,omitempty"`
+}
+
+// Revision returns a human-readable identifier of the locked source state.
+func (s *Source) Revision() string {
+	if s.Commit != "" {
+		return s.Commit
+	}
+	return s.Checksum
+}
+
+// ShortRevision abbreviates a commit hash or checksum for display.
+func ShortRevision(revision string) string {
+	prefix := ""
+	if algorithm, digest, ok := strings.Cut(revision, ":"); ok {
+		prefix, revision = algorithm+":", digest
+	}
+	if len(revision) > shortRevisionLength {
+		revision = revision[:shortRevisionLength]
+	}
+	return prefix + revision
+}
+
+// Target is the locked state of the files written by a single target.
+type Target struct {
+	Name string `yaml:"name"`
+	// Files maps output paths to the content hash that was installed.
+	Files map[string]string `yaml:"files"`
+}
+
+// Lockfile pins every fetched source to the exact revision that was installed
+// and records the files written by every target.
+type Lockfile struct {
+	Version int      `yaml:"version"`
+	Sources []Source `yaml:"sources"`
+	Targets []Target `yaml:"targets,omitempty"`
+}
+
+func New() *Lockfile {
+	return &Lockfile{
+		Version: 1,
+		Sources: []Source{},
+	}
+}
+
+// PathForConfig returns the lockfile path that belongs to the given configuration file,
+// e.g. "pim.yaml" is locked by "pim.lock".
+func PathForConfig(configPath string) string {
+	return strings.TrimSuffix(configPath, filepath.Ext(configPath)) + FileExtension
+}
+
+// Load reads the lockfile at the given path. A missing lockfile results in an empty one.
+func Load(fs afero.Fs, path string) (*Lockfile, error) {
+	data, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	lock := New()
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile '%s': %w", path, err)
+	}
+
+	return lock, nil
+}
+
+// Save writes the lockfile to the given path with sources and targets sorted by name.
+func (l *Lockfile) Save(fs afero.Fs, path string) error {
+	sort.Slice(l.Sources, func(i, j int) bool {
+		return l.Sources[i].Name < l.Sources[j].Name
+	})
+	sort.Slice(l.Targets, func(i, j int) bool {
+		return l.Targets[i].Name < l.Targets[j].Name
+	})
+
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	if err := afero.WriteFile(fs, path, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// Source returns the locked source with the given name, or nil if it is not locked.
+func (l *Lockfile) Source(name string) *Source {
+	for i := range l.Sources {
+		if l.Sources[i].Name == name {
+			return &l.Sources[i]
+		}
+	}
+	return nil
+}
+
+// Put adds the source to the lockfile, replacing any existing entry with the same name.
+func (l *Lockfile) Put(source Source) {
+	if existing := l.Source(source.Name); existing != nil {
+		*existing = source
+		return
+	}
+
+	l.Sources = append(l.Sources, source)
+}
+
+// Remove deletes the source with the given name from the lockfile.
+func (l *Lockfile) Remove(name string) {
+	for i := range l.Sources {
+		if l.Sources[i].Name == name {
+			l.Sources = append(l.Sources[:i], l.Sources[i+1:]...)
+			return
+		}
+	}
+}
+
+// Target returns the locked target with the given name, or nil if it is not locked.
+func (l *Lockfile) Target(name string) *Target {
+	for i := range l.Targets {
+		if l.Targets[i].Name == name {
+			return &l.Targets[i]
+		}
+	}
+	return nil
+}
+
+// PutTarget adds the target to the lockfile, replacing any existing entry with the same name.
+func (l *Lockfile) PutTarget(target Target) {
+	if existing := l.Target(target.Name); existing != nil {
+		*existing = target
+		return
+	}
+
+	l.Targets = append(l.Targets, target)
+}
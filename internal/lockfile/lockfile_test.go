@@ -0,0 +1,150 @@
+package lockfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestPathForConfig(t *testing.T) {
+	tests := []struct {
+		configPath string
+		expected   string
+	}{
+		{"pim.yaml", "pim.lock"},
+		{"config/pim.yml", "config/pim.lock"},
+		{"custom", "custom.lock"},
+	}
+
+	for _, tt := range tests {
+		if got := PathForConfig(tt.configPath); got != tt.expected {
+			t.Errorf("PathForConfig(%q) = %q, expected %q", tt.configPath, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadMissingLockfile(t *testing.T) {
+	lock, err := Load(afero.NewMemMapFs(), "pim.lock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lock.Version != 1 {
+		t.Errorf("expected version 1, got %d", lock.Version)
+	}
+	if len(lock.Sources) != 0 {
+		t.Errorf("expected no sources, got %d", len(lock.Sources))
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	lock := New()
+	lock.Put(Source{
+		Name:   "zeta",
+		URL:    "github.com/org/zeta",
+		Commit: "0123456789abcdef0123456789abcdef01234567",
+		Files: map[string]string{
+			"b.md": "sha256:bb",
+			"a.md": "sha256:aa",
+		},
+	})
+	lock.Put(Source{
+		Name:     "alpha",
+		URL:      "https://example.com/prompts.zip",
+		Checksum: "sha256:cc",
+	})
+
+	if err := lock.Save(fs, "pim.lock"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := afero.ReadFile(fs, "pim.lock")
+	if err != nil {
+		t.Fatalf("failed to read lockfile: %v", err)
+	}
+	content := string(data)
+	if !strings.HasPrefix(content, "# This file is generated by PIM") {
+		t.Errorf("expected generated header, got:\n%s", content)
+	}
+	if strings.Index(content, "name: alpha") > strings.Index(content, "name: zeta") {
+		t.Errorf("expected sources to be sorted by name, got:\n%s", content)
+	}
+
+	loaded, err := Load(fs, "pim.lock")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	zeta := loaded.Source("zeta")
+	if zeta == nil {
+		t.Fatal("expected source 'zeta' to be locked")
+	}
+	if zeta.Revision() != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("unexpected revision %q", zeta.Revision())
+	}
+	if zeta.Files["a.md"] != "sha256:aa" || zeta.Files["b.md"] != "sha256:bb" {
+		t.Errorf("unexpected files %v", zeta.Files)
+	}
+
+	alpha := loaded.Source("alpha")
+	if alpha == nil {
+		t.Fatal("expected source 'alpha' to be locked")
+	}
+	if alpha.Revision() != "sha256:cc" {
+		t.Errorf("unexpected revision %q", alpha.Revision())
+	}
+}
+
+func TestPutReplacesExistingSource(t *testing.T) {
+	lock := New()
+	lock.Put(Source{Name: "s1", URL: "one", Commit: "a"})
+	lock.Put(Source{Name: "s1", URL: "one", Commit: "b"})
+
+	if len(lock.Sources) != 1 {
+		t.Fatalf("expected 1 source, got %d", len(lock.Sources))
+	}
+	if lock.Source("s1").Commit != "b" {
+		t.Errorf("expected commit to be replaced, got %q", lock.Source("s1").Commit)
+	}
+	if lock.Source("missing") != nil {
+		t.Error("expected nil for unknown source")
+	}
+}
+
+func TestPutTarget(t *testing.T) {
+	lock := New()
+	lock.PutTarget(Target{Name: "t1", Files: map[string]string{"out.md": "sha256:1"}})
+	lock.PutTarget(Target{Name: "t2", Files: map[string]string{"docs/a.md": "sha256:2"}})
+	lock.PutTarget(Target{Name: "t1", Files: map[string]string{"out.md": "sha256:3"}})
+
+	if len(lock.Targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(lock.Targets))
+	}
+	if lock.Target("t1").Files["out.md"] != "sha256:3" {
+		t.Errorf("expected target to be replaced, got %v", lock.Target("t1").Files)
+	}
+	if lock.Target("missing") != nil {
+		t.Error("expected nil for unknown target")
+	}
+}
+
+func TestShortRevision(t *testing.T) {
+	tests := []struct {
+		revision string
+		expected string
+	}{
+		{"0123456789abcdef0123456789abcdef01234567", "0123456789ab"},
+		{"sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e", "sha256:2cf24dba5fb0"},
+		{"abc", "abc"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ShortRevision(tt.revision); got != tt.expected {
+			t.Errorf("ShortRevision(%q) = %q, expected %q", tt.revision, got, tt.expected)
+		}
+	}
+}
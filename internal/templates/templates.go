@@ -31,3 +31,18 @@ func RenderGenerateInstructionsPrompt(instructionsDir string) (string, error) {
 
 	return buf.String(), nil
 }
+
+// Render executes the template content with the given data. Referencing a missing key of a map is an error.
+func Render(name, content string, data any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
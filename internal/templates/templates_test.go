@@ -15,3 +15,48 @@ func TestRenderGenerateInstructionsPrompt(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 }
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		data    map[string]any
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "variables",
+			content: "# {{ .ProjectName }}\nUse {{ .Language }} {{ .Version }}.",
+			data:    map[string]any{"ProjectName": "pim", "Language": "Go", "Version": 1.25},
+			want:    "# pim\nUse Go 1.25.",
+		},
+		{
+			name:    "no actions",
+			content: "Plain text",
+			want:    "Plain text",
+		},
+		{
+			name:    "missing variable",
+			content: "{{ .Missing }}",
+			data:    map[string]any{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			content: "{{ .ProjectName",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.name, tt.content, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
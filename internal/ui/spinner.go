@@ -2,10 +2,13 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
 )
 
 type SpinnerDialog struct {
@@ -69,7 +72,13 @@ func (s SpinnerDialog) View() string {
 }
 
 // RunWithSpinner displays a Spinner while executing the provided function.
+// When stdout is not a terminal, the text is printed once instead.
 func RunWithSpinner(text string, fn func() error) error {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print(text)
+		return fn()
+	}
+
 	dialog := NewSpinnerDialog(text)
 
 	p := tea.NewProgram(dialog)
@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// Task is a unit of work run by RunTasks.
+type Task struct {
+	// Label describes the task while it is waiting or running.
+	Label string
+	// Run performs the task and returns the message displayed when it succeeds.
+	Run func() (string, error)
+}
+
+// TaskResult is the outcome of a single task.
+type TaskResult struct {
+	Message string
+	Err     error
+}
+
+type TaskState int
+
+const (
+	TaskPending TaskState = iota
+	TaskRunning
+	TaskDone
+	TaskFailed
+)
+
+// TasksDialog is a Bubble Tea model that displays the state of concurrently running tasks, one per line.
+type TasksDialog struct {
+	Spinner     spinner.Model
+	Tasks       []Task
+	States      []TaskState
+	Results     []TaskResult
+	StyleConfig StyleConfig
+	done        bool
+	cancelled   bool
+}
+
+var _ tea.Model = (*TasksDialog)(nil)
+
+type taskStateMsg struct {
+	index  int
+	state  TaskState
+	result TaskResult
+}
+
+type tasksDoneMsg struct{}
+
+func NewTasksDialog(tasks []Task) TasksDialog {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = DefaultStyleConfig().PromptStyle
+
+	return TasksDialog{
+		Spinner:     s,
+		Tasks:       tasks,
+		States:      make([]TaskState, len(tasks)),
+		Results:     make([]TaskResult, len(tasks)),
+		StyleConfig: DefaultStyleConfig(),
+	}
+}
+
+func (d TasksDialog) Init() tea.Cmd {
+	return d.Spinner.Tick
+}
+
+func (d TasksDialog) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			d.cancelled = true
+			return d, tea.Quit
+		}
+	case taskStateMsg:
+		d.States[msg.index] = msg.state
+		d.Results[msg.index] = msg.result
+	case tasksDoneMsg:
+		d.done = true
+		return d, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		d.Spinner, cmd = d.Spinner.Update(msg)
+		return d, cmd
+	}
+	return d, nil
+}
+
+func (d TasksDialog) View() string {
+	var b strings.Builder
+
+	for i, task := range d.Tasks {
+		switch d.States[i] {
+		case TaskPending:
+			b.WriteString(d.StyleConfig.NormalStyle.Render("  " + task.Label))
+		case TaskRunning:
+			b.WriteString(d.Spinner.View() + " " + task.Label)
+		case TaskDone:
+			b.WriteString(d.StyleConfig.HighlightStyle.Render("✓") + " " + d.Results[i].Message)
+		case TaskFailed:
+			b.WriteString("✗ " + task.Label + ": " + d.Results[i].Err.Error())
+		}
+		b.WriteString("\n")
+	}
+
+	if !d.done && !d.cancelled {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RunTasks runs the tasks concurrently, with at most jobs tasks running at the same time,
+// and returns their results in task order.
+//
+// When out is a terminal, the state of every task is displayed on its own line. Otherwise
+// the label of every started task and the message of every finished task are written to out.
+func RunTasks(tasks []Task, jobs int, out io.Writer) []TaskResult {
+	if file, ok := out.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		return runTasksWithDialog(tasks, jobs)
+	}
+
+	var mu sync.Mutex
+	return runTasks(tasks, jobs, func(msg taskStateMsg) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch msg.state {
+		case TaskRunning:
+			_, _ = fmt.Fprintln(out, tasks[msg.index].Label)
+		case TaskDone:
+			_, _ = fmt.Fprintln(out, msg.result.Message)
+		}
+	})
+}
+
+func runTasksWithDialog(tasks []Task, jobs int) []TaskResult {
+	p := tea.NewProgram(NewTasksDialog(tasks))
+
+	resultsChan := make(chan []TaskResult, 1)
+	go func() {
+		resultsChan <- runTasks(tasks, jobs, func(msg taskStateMsg) {
+			p.Send(msg)
+		})
+		p.Send(tasksDoneMsg{})
+	}()
+
+	finalModel, err := p.Run()
+	if err != nil {
+		// The tasks keep running without the dialog.
+		return <-resultsChan
+	}
+
+	// When the dialog was cancelled (e.g., Ctrl+C), report unfinished tasks as cancelled.
+	if result, ok := finalModel.(TasksDialog); ok && !result.done {
+		results := make([]TaskResult, len(tasks))
+		for i, state := range result.States {
+			results[i] = result.Results[i]
+			if state == TaskPending || state == TaskRunning {
+				results[i].Err = context.Canceled
+			}
+		}
+		return results
+	}
+
+	return <-resultsChan
+}
+
+// runTasks runs the tasks with a pool of jobs workers and reports every state change to notify.
+func runTasks(tasks []Task, jobs int, notify func(taskStateMsg)) []TaskResult {
+	jobs = max(1, min(jobs, len(tasks)))
+	results := make([]TaskResult, len(tasks))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				notify(taskStateMsg{index: index, state: TaskRunning})
+
+				message, err := tasks[index].Run()
+				results[index] = TaskResult{Message: message, Err: err}
+
+				state := TaskDone
+				if err != nil {
+					state = TaskFailed
+				}
+				notify(taskStateMsg{index: index, state: state, result: results[index]})
+			}
+		}()
+	}
+
+	for index := range tasks {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunTasks(t *testing.T) {
+	var running, maxRunning atomic.Int32
+
+	tasks := make([]Task, 6)
+	for i := range tasks {
+		tasks[i] = Task{
+			Label: fmt.Sprintf("task %d", i),
+			Run: func() (string, error) {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					previous := maxRunning.Load()
+					if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+				if i%3 == 0 {
+					return "", fmt.Errorf("task %d failed", i)
+				}
+				return fmt.Sprintf("task %d done", i), nil
+			},
+		}
+	}
+
+	var out bytes.Buffer
+	results := RunTasks(tasks, 2, &out)
+
+	if len(results) != len(tasks) {
+		t.Fatalf("expected %d results, got %d", len(tasks), len(results))
+	}
+	for i, result := range results {
+		if i%3 == 0 {
+			if result.Err == nil || result.Err.Error() != fmt.Sprintf("task %d failed", i) {
+				t.Errorf("expected error for task %d, got %v", i, result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || result.Message != fmt.Sprintf("task %d done", i) {
+			t.Errorf("unexpected result for task %d: %+v", i, result)
+		}
+	}
+
+	if maxRunning.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent tasks, got %d", maxRunning.Load())
+	}
+	if !strings.Contains(out.String(), "task 1\n") || !strings.Contains(out.String(), "task 1 done\n") {
+		t.Errorf("expected progress output, got:\n%s", out.String())
+	}
+}
+
+func TestTasksDialogView(t *testing.T) {
+	dialog := NewTasksDialog([]Task{{Label: "pending"}, {Label: "done"}, {Label: "failed"}})
+	dialog.States = []TaskState{TaskPending, TaskDone, TaskFailed}
+	dialog.Results = []TaskResult{{}, {Message: "all good"}, {Err: errors.New("boom")}}
+
+	view := dialog.View()
+	for _, fragment := range []string{"pending", "all good", "✗ failed: boom"} {
+		if !strings.Contains(view, fragment) {
+			t.Errorf("expected view to contain %q, got:\n%s", fragment, view)
+		}
+	}
+}
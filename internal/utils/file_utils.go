@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"errors"
 	"io"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/afero"
@@ -36,7 +38,65 @@ func CopyFile(fs afero.Fs, src, dst string) error {
 	return fs.Chmod(dst, srcInfo.Mode())
 }
 
+// ErrLinkNotSupported is returned when the filesystem cannot create links of the requested kind.
+var ErrLinkNotSupported = errors.New("links are not supported by the filesystem")
+
+// HardLinker is implemented by filesystems that can create hard links.
+type HardLinker interface {
+	LinkIfPossible(oldname, newname string) error
+}
+
+// SymlinkFile creates a symbolic link at dst pointing to src. The link is relative to the directory of dst when
+// both paths can be made absolute, so that it survives moving the tree that contains them.
+func SymlinkFile(fs afero.Fs, src, dst string) error {
+	linker, ok := fs.(afero.Linker)
+	if !ok {
+		return ErrLinkNotSupported
+	}
+	if err := fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	target := src
+	absSrc, srcErr := filepath.Abs(src)
+	absDst, dstErr := filepath.Abs(dst)
+	if srcErr == nil && dstErr == nil {
+		if rel, err := filepath.Rel(filepath.Dir(absDst), absSrc); err == nil {
+			target = rel
+		}
+	}
+
+	err := linker.SymlinkIfPossible(target, dst)
+	if errors.Is(err, afero.ErrNoSymlink) {
+		return ErrLinkNotSupported
+	}
+	return err
+}
+
+// HardLinkFile creates a hard link at dst to src.
+func HardLinkFile(fs afero.Fs, src, dst string) error {
+	var link func(oldname, newname string) error
+	switch linker := fs.(type) {
+	case HardLinker:
+		link = linker.LinkIfPossible
+	case *afero.OsFs:
+		link = os.Link
+	default:
+		return ErrLinkNotSupported
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return link(src, dst)
+}
+
 func HasMdExtension(path string) bool {
 	ext := filepath.Ext(path)
 	return ext == ".md"
 }
+
+// HasWriteFlag reports whether the flags passed to OpenFile allow modifying the file.
+func HasWriteFlag(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
@@ -76,3 +77,22 @@ func WriteFrontmatter(w io.Writer, v any) error {
 
 	return err
 }
+
+// StripFrontmatter returns the content without its leading frontmatter block.
+// Content without a complete frontmatter block is returned unchanged.
+func StripFrontmatter(content []byte) []byte {
+	firstLine, rest, found := bytes.Cut(content, []byte("\n"))
+	if !found || strings.TrimSpace(string(firstLine)) != delimiter {
+		return content
+	}
+
+	for len(rest) > 0 {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if strings.TrimSpace(string(line)) == delimiter {
+			return rest
+		}
+	}
+
+	return content
+}
@@ -181,3 +181,55 @@ func TestWriteFrontmatter_RoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestStripFrontmatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "frontmatter",
+			content: "---\nkey: value\n---\n# Content\n",
+			want:    "# Content\n",
+		},
+		{
+			name:    "empty frontmatter",
+			content: "---\n---\nContent",
+			want:    "Content",
+		},
+		{
+			name:    "frontmatter with whitespace",
+			content: "   ---   \nkey: value\n   ---   \nContent",
+			want:    "Content",
+		},
+		{
+			name:    "frontmatter only",
+			content: "---\nkey: value\n---",
+			want:    "",
+		},
+		{
+			name:    "no frontmatter",
+			content: "# Content\n---\nkey: value\n---\n",
+			want:    "# Content\n---\nkey: value\n---\n",
+		},
+		{
+			name:    "missing closing delimiter",
+			content: "---\nkey: value\nContent",
+			want:    "---\nkey: value\nContent",
+		},
+		{
+			name:    "empty content",
+			content: "",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(StripFrontmatter([]byte(tt.content))); got != tt.want {
+				t.Errorf("StripFrontmatter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+)
+
+const hashPrefix = "sha256:"
+
+// HashFile returns the SHA-256 digest of the file content in the form "sha256:<hex>".
+func HashFile(fs afero.Fs, path string) (string, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashDir returns a digest of the whole directory tree in the form "sha256:<hex>".
+//
+// The digest covers relative paths and file contents, so it changes whenever a file
+// is added, removed, renamed or modified. The ".git" directory is ignored.
+func HashDir(fs afero.Fs, dir string) (string, error) {
+	fileHashes := make(map[string]string)
+
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fileHash, err := HashFile(fs, path)
+		if err != nil {
+			return err
+		}
+		fileHashes[filepath.ToSlash(relPath)] = fileHash
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash directory '%s': %w", dir, err)
+	}
+
+	paths := make([]string, 0, len(fileHashes))
+	for path := range fileHashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(h, "%s %s\n", fileHashes[path], path)
+	}
+
+	return hashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
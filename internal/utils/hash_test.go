@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestHashFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "file.txt", []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	got, err := HashFile(fs, "file.txt")
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+
+	expected := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got != expected {
+		t.Errorf("HashFile() = %q, expected %q", got, expected)
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	newTree := func(files map[string]string) afero.Fs {
+		fs := afero.NewMemMapFs()
+		for path, content := range files {
+			if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		return fs
+	}
+
+	base := map[string]string{
+		"src/a.md":     "A",
+		"src/sub/b.md": "B",
+	}
+
+	baseHash, err := HashDir(newTree(base), "src")
+	if err != nil {
+		t.Fatalf("HashDir() error = %v", err)
+	}
+	if !strings.HasPrefix(baseHash, "sha256:") {
+		t.Errorf("expected sha256 prefix, got %q", baseHash)
+	}
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		expectSame bool
+	}{
+		{
+			name:       "same content",
+			files:      map[string]string{"src/a.md": "A", "src/sub/b.md": "B"},
+			expectSame: true,
+		},
+		{
+			name:       "git directory is ignored",
+			files:      map[string]string{"src/a.md": "A", "src/sub/b.md": "B", "src/.git/HEAD": "ref"},
+			expectSame: true,
+		},
+		{
+			name:       "modified file",
+			files:      map[string]string{"src/a.md": "A2", "src/sub/b.md": "B"},
+			expectSame: false,
+		},
+		{
+			name:       "renamed file",
+			files:      map[string]string{"src/a.md": "A", "src/sub/c.md": "B"},
+			expectSame: false,
+		},
+		{
+			name:       "added file",
+			files:      map[string]string{"src/a.md": "A", "src/sub/b.md": "B", "src/c.md": "C"},
+			expectSame: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashDir(newTree(tt.files), "src")
+			if err != nil {
+				t.Fatalf("HashDir() error = %v", err)
+			}
+			if (got == baseHash) != tt.expectSame {
+				t.Errorf("HashDir() = %q, base %q, expected same: %v", got, baseHash, tt.expectSame)
+			}
+		})
+	}
+}
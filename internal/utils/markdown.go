@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const maxHeadingLevel = 6
+
+// Heading is an ATX heading of a markdown document, e.g. "## Usage".
+type Heading struct {
+	Level int
+	Text  string
+}
+
+// MarkdownHeadings returns the ATX headings of the markdown content in document order.
+// Lines inside fenced code blocks are ignored.
+func MarkdownHeadings(content []byte) []Heading {
+	var headings []Heading
+	forEachMarkdownLine(content, func(line []byte, inCode bool) {
+		if inCode {
+			return
+		}
+		if heading, ok := parseHeading(trimLineEnding(line)); ok {
+			headings = append(headings, heading)
+		}
+	})
+	return headings
+}
+
+// ShiftHeadings changes the level of every ATX heading of the markdown content by offset.
+// Levels are kept between 1 and 6, and lines inside fenced code blocks are left unchanged.
+func ShiftHeadings(content []byte, offset int) []byte {
+	if offset == 0 {
+		return content
+	}
+
+	var shifted bytes.Buffer
+	forEachMarkdownLine(content, func(line []byte, inCode bool) {
+		heading, ok := parseHeading(trimLineEnding(line))
+		if inCode || !ok {
+			shifted.Write(line)
+			return
+		}
+
+		level := min(max(heading.Level+offset, 1), maxHeadingLevel)
+		start := bytes.IndexByte(line, '#')
+		shifted.Write(line[:start])
+		shifted.WriteString(strings.Repeat("#", level))
+		shifted.Write(line[start+heading.Level:])
+	})
+	return shifted.Bytes()
+}
+
+// forEachMarkdownLine calls fn for every line of content, including its line ending, and reports
+// whether the line is part of a fenced code block, including the fences themselves.
+func forEachMarkdownLine(content []byte, fn func(line []byte, inCode bool)) {
+	var fence string
+
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 {
+			end = len(content)
+		}
+		line := content[:end]
+		content = content[end:]
+
+		marker := codeFence(trimLineEnding(line))
+		switch {
+		case fence == "" && marker != "":
+			fence = marker
+			fn(line, true)
+		case fence != "":
+			// A closing fence uses the same character and is at least as long as the opening fence.
+			if strings.HasPrefix(marker, fence) && len(bytes.TrimSpace(line)) == len(marker) {
+				fence = ""
+			}
+			fn(line, true)
+		default:
+			fn(line, false)
+		}
+	}
+}
+
+func trimLineEnding(line []byte) []byte {
+	return bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+}
+
+// codeFence returns the fence marker (``` or ~~~, possibly longer) that starts the line, if any.
+func codeFence(line []byte) string {
+	trimmed := bytes.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+
+	char := trimmed[0]
+	if char != '`' && char != '~' {
+		return ""
+	}
+
+	length := 0
+	for length < len(trimmed) && trimmed[length] == char {
+		length++
+	}
+	if length < 3 {
+		return ""
+	}
+	return string(trimmed[:length])
+}
+
+// parseHeading parses an ATX heading line.
+func parseHeading(line []byte) (Heading, bool) {
+	trimmed := bytes.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return Heading{}, false
+	}
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > maxHeadingLevel {
+		return Heading{}, false
+	}
+
+	rest := trimmed[level:]
+	if len(rest) > 0 && rest[0] != ' ' && rest[0] != '\t' {
+		return Heading{}, false
+	}
+
+	text := strings.TrimSpace(string(rest))
+	// Remove an optional closing sequence of '#' characters.
+	if stripped := strings.TrimRight(text, "#"); stripped == "" || strings.HasSuffix(stripped, " ") {
+		text = strings.TrimSpace(stripped)
+	}
+
+	return Heading{Level: level, Text: text}, true
+}
+
+// HeadingAnchors returns the anchors GitHub generates for the headings of a document.
+// Duplicate anchors are made unique with a numeric suffix.
+func HeadingAnchors(headings []Heading) []string {
+	anchors := make([]string, len(headings))
+	seen := make(map[string]int)
+
+	for index, heading := range headings {
+		anchor := headingSlug(heading.Text)
+		if count, ok := seen[anchor]; ok {
+			seen[anchor] = count + 1
+			anchor = fmt.Sprintf("%s-%d", anchor, count+1)
+		} else {
+			seen[anchor] = 0
+		}
+		anchors[index] = anchor
+	}
+
+	return anchors
+}
+
+// headingSlug lowercases the heading text, removes punctuation and replaces spaces by hyphens.
+func headingSlug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarkdownHeadings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Heading
+	}{
+		{
+			name:    "atx headings",
+			content: "# Title\n\nText\n\n## Usage ##\n### Deep\r\n####### Not a heading\n#hashtag\n",
+			want: []Heading{
+				{Level: 1, Text: "Title"},
+				{Level: 2, Text: "Usage"},
+				{Level: 3, Text: "Deep"},
+			},
+		},
+		{
+			name:    "headings in fenced code blocks are ignored",
+			content: "# Title\n```sh\n# comment\n```\n~~~~\n# comment\n~~~\n# still code\n~~~~\n## After\n",
+			want: []Heading{
+				{Level: 1, Text: "Title"},
+				{Level: 2, Text: "After"},
+			},
+		},
+		{
+			name:    "indented code is not a heading",
+			content: "    # code\n   # Heading\n",
+			want: []Heading{
+				{Level: 1, Text: "Heading"},
+			},
+		},
+		{
+			name:    "no headings",
+			content: "Text only\n",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownHeadings([]byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MarkdownHeadings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	headings := []Heading{
+		{Level: 1, Text: "Getting Started"},
+		{Level: 2, Text: "Use `pim install`!"},
+		{Level: 2, Text: "Getting Started"},
+		{Level: 2, Text: "Getting Started"},
+		{Level: 3, Text: "Überblick & snake_case"},
+	}
+
+	want := []string{
+		"getting-started",
+		"use-pim-install",
+		"getting-started-1",
+		"getting-started-2",
+		"überblick--snake_case",
+	}
+
+	if got := HeadingAnchors(headings); !reflect.DeepEqual(got, want) {
+		t.Errorf("HeadingAnchors() = %v, want %v", got, want)
+	}
+}
+
+func TestShiftHeadings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		offset  int
+		want    string
+	}{
+		{
+			name:    "increase levels",
+			content: "# Title\n\nText with # hash\n## Usage ##\r\n",
+			offset:  1,
+			want:    "## Title\n\nText with # hash\n### Usage ##\r\n",
+		},
+		{
+			name:    "decrease levels",
+			content: "  ### Title\n#### Usage",
+			offset:  -2,
+			want:    "  # Title\n## Usage",
+		},
+		{
+			name:    "levels are clamped",
+			content: "# Title\n##### Deep\n",
+			offset:  3,
+			want:    "#### Title\n###### Deep\n",
+		},
+		{
+			name:    "fenced code blocks are unchanged",
+			content: "# Title\n```md\n# Example\n```\n",
+			offset:  1,
+			want:    "## Title\n```md\n# Example\n```\n",
+		},
+		{
+			name:    "no offset",
+			content: "# Title\n",
+			offset:  0,
+			want:    "# Title\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ShiftHeadings([]byte(tt.content), tt.offset)); got != tt.want {
+				t.Errorf("ShiftHeadings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
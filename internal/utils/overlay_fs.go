@@ -0,0 +1,432 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+type ChangeKind string
+
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change describes how a file in the overlay differs from the base filesystem.
+type Change struct {
+	Path string
+	Kind ChangeKind
+}
+
+// OverlayFs is a copy-on-write filesystem that keeps every change, including removals,
+// in memory while reading unchanged files from the base filesystem.
+//
+// Unlike afero.CopyOnWriteFs, removed base files disappear from the overlay, so the
+// overlay reflects exactly what the base filesystem would look like after the changes.
+type OverlayFs struct {
+	base  afero.Fs
+	layer afero.Fs
+
+	mu      sync.Mutex
+	removed map[string]bool
+	written map[string]bool
+}
+
+var _ afero.Fs = (*OverlayFs)(nil)
+
+func NewOverlayFs(base afero.Fs) *OverlayFs {
+	return &OverlayFs{
+		base:    base,
+		layer:   afero.NewMemMapFs(),
+		removed: make(map[string]bool),
+		written: make(map[string]bool),
+	}
+}
+
+func (o *OverlayFs) Name() string {
+	return "OverlayFs"
+}
+
+func (o *OverlayFs) inLayer(name string) bool {
+	_, err := o.layer.Stat(name)
+	return err == nil
+}
+
+// isRemoved reports whether the path or one of its parents was removed from the base filesystem.
+func (o *OverlayFs) isRemoved(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for path := filepath.Clean(name); ; path = filepath.Dir(path) {
+		if o.removed[path] {
+			return true
+		}
+		if filepath.Dir(path) == path {
+			return false
+		}
+	}
+}
+
+// exists reports whether the path is visible in the overlay.
+func (o *OverlayFs) exists(name string) bool {
+	_, err := o.Stat(name)
+	return err == nil
+}
+
+func (o *OverlayFs) Stat(name string) (os.FileInfo, error) {
+	if o.inLayer(name) {
+		return o.layer.Stat(name)
+	}
+	if o.isRemoved(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return o.base.Stat(name)
+}
+
+func (o *OverlayFs) Open(name string) (afero.File, error) {
+	var file afero.File
+	var err error
+
+	switch {
+	case o.inLayer(name):
+		file, err = o.layer.Open(name)
+	case o.isRemoved(name):
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	default:
+		file, err = o.base.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return &overlayDir{File: file, fs: o, name: name}, nil
+	}
+
+	return file, nil
+}
+
+func (o *OverlayFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if !HasWriteFlag(flag) {
+		return o.Open(name)
+	}
+
+	exists := o.exists(name)
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	if !o.inLayer(name) {
+		if exists && flag&os.O_TRUNC == 0 {
+			if err := o.copyToLayer(name); err != nil {
+				return nil, err
+			}
+		} else if err := o.layer.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	o.mu.Lock()
+	o.written[filepath.Clean(name)] = true
+	o.mu.Unlock()
+
+	return o.layer.OpenFile(name, flag, perm)
+}
+
+func (o *OverlayFs) Create(name string) (afero.File, error) {
+	return o.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (o *OverlayFs) Mkdir(name string, perm os.FileMode) error {
+	if o.exists(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	return o.layer.MkdirAll(name, perm)
+}
+
+func (o *OverlayFs) MkdirAll(path string, perm os.FileMode) error {
+	if info, err := o.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	return o.layer.MkdirAll(path, perm)
+}
+
+func (o *OverlayFs) Remove(name string) error {
+	if !o.exists(name) {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+
+	if o.inLayer(name) {
+		if err := o.layer.Remove(name); err != nil {
+			return err
+		}
+	}
+
+	o.mu.Lock()
+	o.removed[filepath.Clean(name)] = true
+	o.mu.Unlock()
+
+	return nil
+}
+
+func (o *OverlayFs) RemoveAll(path string) error {
+	if err := o.layer.RemoveAll(path); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.removed[filepath.Clean(path)] = true
+	o.mu.Unlock()
+
+	return nil
+}
+
+func (o *OverlayFs) Rename(oldname, newname string) error {
+	info, err := o.Stat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	if info.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrInvalid}
+	}
+
+	content, err := afero.ReadFile(o, oldname)
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(o, newname, content, info.Mode()); err != nil {
+		return err
+	}
+
+	return o.Remove(oldname)
+}
+
+func (o *OverlayFs) Chmod(name string, mode os.FileMode) error {
+	if err := o.ensureInLayer(name); err != nil {
+		return err
+	}
+	return o.layer.Chmod(name, mode)
+}
+
+func (o *OverlayFs) Chown(name string, uid, gid int) error {
+	if err := o.ensureInLayer(name); err != nil {
+		return err
+	}
+	return o.layer.Chown(name, uid, gid)
+}
+
+func (o *OverlayFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := o.ensureInLayer(name); err != nil {
+		return err
+	}
+	return o.layer.Chtimes(name, atime, mtime)
+}
+
+func (o *OverlayFs) ensureInLayer(name string) error {
+	if o.inLayer(name) {
+		return nil
+	}
+
+	info, err := o.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return o.layer.MkdirAll(name, info.Mode().Perm())
+	}
+	return o.copyToLayer(name)
+}
+
+func (o *OverlayFs) copyToLayer(name string) error {
+	info, err := o.base.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	content, err := afero.ReadFile(o.base, name)
+	if err != nil {
+		return err
+	}
+
+	if err := o.layer.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(o.layer, name, content, info.Mode())
+}
+
+// readDir lists the directory entries visible in the overlay, sorted by name.
+func (o *OverlayFs) readDir(name string) ([]os.FileInfo, error) {
+	entriesByName := make(map[string]os.FileInfo)
+
+	if o.inLayer(name) {
+		entries, err := afero.ReadDir(o.layer, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			entriesByName[entry.Name()] = entry
+		}
+	}
+
+	if !o.isRemoved(name) {
+		if info, err := o.base.Stat(name); err == nil && info.IsDir() {
+			entries, err := afero.ReadDir(o.base, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if _, ok := entriesByName[entry.Name()]; ok {
+					continue
+				}
+				if o.isRemoved(filepath.Join(name, entry.Name())) {
+					continue
+				}
+				entriesByName[entry.Name()] = entry
+			}
+		}
+	}
+
+	entries := make([]os.FileInfo, 0, len(entriesByName))
+	for _, entry := range entriesByName {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// Changes lists the files that differ between the overlay and the base filesystem, sorted by path.
+// Files that were rewritten with identical content are not reported.
+func (o *OverlayFs) Changes() ([]Change, error) {
+	o.mu.Lock()
+	written := sortedKeys(o.written)
+	removed := sortedKeys(o.removed)
+	o.mu.Unlock()
+
+	changesByPath := make(map[string]ChangeKind)
+
+	for _, path := range written {
+		info, err := o.layer.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		baseInfo, err := o.base.Stat(path)
+		if err != nil || baseInfo.IsDir() {
+			changesByPath[path] = ChangeCreated
+			continue
+		}
+
+		same, err := sameContent(o.layer, o.base, path)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			changesByPath[path] = ChangeUpdated
+		}
+	}
+
+	for _, root := range removed {
+		if _, err := o.base.Stat(root); err != nil {
+			continue
+		}
+
+		err := afero.Walk(o.base, root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && !o.exists(path) {
+				changesByPath[filepath.Clean(path)] = ChangeDeleted
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes := make([]Change, 0, len(changesByPath))
+	for _, path := range sortedKeys(changesByPath) {
+		changes = append(changes, Change{Path: path, Kind: changesByPath[path]})
+	}
+
+	return changes, nil
+}
+
+func sameContent(a, b afero.Fs, path string) (bool, error) {
+	contentA, err := afero.ReadFile(a, path)
+	if err != nil {
+		return false, err
+	}
+	contentB, err := afero.ReadFile(b, path)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(contentA, contentB), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// overlayDir is a directory handle that lists the merged entries of the overlay.
+type overlayDir struct {
+	afero.File
+	fs      *OverlayFs
+	name    string
+	entries []os.FileInfo
+	loaded  bool
+	offset  int
+}
+
+func (d *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.fs.readDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.loaded = entries, true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	count = min(count, len(remaining))
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *overlayDir) Readdirnames(n int) ([]string, error) {
+	entries, err := d.Readdir(n)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, err
+}
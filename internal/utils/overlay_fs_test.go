@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func newOverlayTestBase(t *testing.T) afero.Fs {
+	t.Helper()
+
+	base := afero.NewMemMapFs()
+	files := map[string]string{
+		"out/keep.md":     "keep",
+		"out/update.md":   "old",
+		"out/sub/drop.md": "drop",
+		"other.md":        "other",
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(base, path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	return base
+}
+
+func TestOverlayFsDoesNotModifyBase(t *testing.T) {
+	base := newOverlayTestBase(t)
+	overlay := NewOverlayFs(base)
+
+	if err := overlay.RemoveAll("out"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if err := afero.WriteFile(overlay, "out/new.md", []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	content, err := afero.ReadFile(base, "out/keep.md")
+	if err != nil || string(content) != "keep" {
+		t.Errorf("expected base file to be untouched, got %q, %v", content, err)
+	}
+	if exists, _ := afero.Exists(base, "out/new.md"); exists {
+		t.Error("expected new file to exist only in the overlay")
+	}
+}
+
+func TestOverlayFsRemoveAllHidesBaseFiles(t *testing.T) {
+	base := newOverlayTestBase(t)
+	overlay := NewOverlayFs(base)
+
+	if err := overlay.RemoveAll("out"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if exists, _ := afero.Exists(overlay, "out/keep.md"); exists {
+		t.Error("expected removed file to be hidden")
+	}
+
+	if err := overlay.MkdirAll("out", 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := afero.WriteFile(overlay, "out/keep.md", []byte("keep"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	names, err := afero.ReadDir(overlay, "out")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(names) != 1 || names[0].Name() != "keep.md" {
+		t.Errorf("expected only re-created file to be listed, got %v", names)
+	}
+
+	if exists, _ := afero.Exists(overlay, "other.md"); !exists {
+		t.Error("expected unrelated base file to stay visible")
+	}
+}
+
+func TestOverlayFsChanges(t *testing.T) {
+	base := newOverlayTestBase(t)
+	overlay := NewOverlayFs(base)
+
+	if err := overlay.RemoveAll("out"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	writes := map[string]string{
+		"out/keep.md":   "keep",
+		"out/update.md": "new",
+		"out/create.md": "created",
+	}
+	for path, content := range writes {
+		if err := afero.WriteFile(overlay, path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+
+	expected := []Change{
+		{Path: "out/create.md", Kind: ChangeCreated},
+		{Path: "out/sub/drop.md", Kind: ChangeDeleted},
+		{Path: "out/update.md", Kind: ChangeUpdated},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+}
+
+func TestOverlayFsAppendCopiesBaseContent(t *testing.T) {
+	base := newOverlayTestBase(t)
+	overlay := NewOverlayFs(base)
+
+	file, err := overlay.OpenFile("other.md", os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := file.WriteString("+more"); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+	_ = file.Close()
+
+	content, err := afero.ReadFile(overlay, "other.md")
+	if err != nil || string(content) != "other+more" {
+		t.Errorf("expected appended content, got %q, %v", content, err)
+	}
+}